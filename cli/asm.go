@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/CityOfZion/neo-storm/vm"
+	"github.com/urfave/cli"
+)
+
+func contractAssemble(ctx *cli.Context) error {
+	src := ctx.String("in")
+	if len(src) == 0 {
+		return cli.NewExitError(errNoInput, 1)
+	}
+	out := ctx.String("out")
+	if len(out) == 0 {
+		out = strings.TrimSuffix(src, filepath.Ext(src)) + ".avm"
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	defer f.Close()
+
+	script, err := vm.Assemble(f)
+	if err != nil {
+		return cli.NewExitError(fmt.Errorf("%s: %v", src, err), 1)
+	}
+	if err := ioutil.WriteFile(out, script, os.ModePerm); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	return nil
+}
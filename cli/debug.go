@@ -0,0 +1,543 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/CityOfZion/neo-go/pkg/crypto"
+	"github.com/CityOfZion/neo-go/pkg/util"
+	"github.com/CityOfZion/neo-storm/compiler"
+	"github.com/CityOfZion/neo-storm/vm"
+	"github.com/urfave/cli"
+)
+
+const debugHelp = `Commands:
+  break <line>         set a breakpoint on a line of the contract
+  break <file>:<line>  set a breakpoint on a line of an imported file
+  break @<offset>      set a breakpoint on a bytecode offset
+  delete <offset>      remove the breakpoint on a bytecode offset
+  breakpoints          list the breakpoints
+  step                 run to the next statement, entering function calls
+  next                 run to the next statement, stepping over function calls
+  stepi                execute a single instruction
+  continue             run until a breakpoint is hit or the contract ends
+  where                show the current position
+  stack                show the evaluation stack
+  altstack             show the alt stack
+  locals               show the local variables of the current function
+  storage              show the storage of the contract
+  notifications        show the notifications of the contract
+  help                 show this help
+  quit                 stop debugging`
+
+func contractDebug(ctx *cli.Context) error {
+	src := ctx.String("in")
+	if len(src) == 0 {
+		return cli.NewExitError(errNoInput, 1)
+	}
+	b, err := ioutil.ReadFile(src)
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	o := &compiler.Options{
+		Trigger:   ctx.String("trigger"),
+		VMVersion: ctx.String("vm-version"),
+	}
+	d, err := newDebugger(b, o, ctx.String("op"), ctx.Args())
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	if err := d.addWitnesses(ctx.StringSlice("witness")); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	d.run(os.Stdin, os.Stdout)
+	return nil
+}
+
+// invocation is an invocation of a contract on the local VM.
+type invocation struct {
+	vm     *vm.VM
+	env    *vm.Environment
+	script []byte
+	hash   util.Uint160
+}
+
+// newInvocation loads the script into a new VM with an in-memory
+// environment. If op is not empty the contract is invoked like
+// Main(op string, args []interface{}), else the arguments are passed as the
+// parameters of Main.
+func newInvocation(script []byte, op string, args []string) (*invocation, error) {
+	items := make([]vm.StackItem, len(args))
+	for i, arg := range args {
+		var err error
+		if items[i], err = parseArg(arg); err != nil {
+			return nil, err
+		}
+	}
+
+	inv := &invocation{
+		vm:     vm.New(),
+		env:    vm.NewEnvironment(),
+		script: script,
+		hash:   vm.NewContext(script).ScriptHash(),
+	}
+	inv.env.Attach(inv.vm)
+
+	if op != "" {
+		inv.vm.Estack().Push(vm.NewArray(items))
+		inv.vm.Estack().Push(vm.NewByteArray([]byte(op)))
+	} else {
+		for i := len(items) - 1; i >= 0; i-- {
+			inv.vm.Estack().Push(items[i])
+		}
+	}
+	inv.vm.LoadScript(script)
+	return inv, nil
+}
+
+// addWitnesses makes runtime.CheckWitness succeed for the given addresses
+// or script hashes.
+func (inv *invocation) addWitnesses(witnesses []string) error {
+	for _, w := range witnesses {
+		hash, err := parseScriptHash(w)
+		if err != nil {
+			return err
+		}
+		inv.env.Witnesses = append(inv.env.Witnesses, hash)
+	}
+	return nil
+}
+
+// debugger runs a contract step by step on the local VM.
+type debugger struct {
+	*invocation
+	info *compiler.DebugInfo
+
+	// breakpoints holds the bytecode offsets to break on.
+	breakpoints map[int]bool
+
+	// source holds the lines of the files of the contract, the contract
+	// itself is stored under the empty name.
+	source map[string][]string
+
+	out io.Writer
+}
+
+// newDebugger compiles the contract and loads it into a new VM, see
+// newInvocation.
+func newDebugger(src []byte, o *compiler.Options, op string, args []string) (*debugger, error) {
+	script, info, err := compiler.CompileWithDebugInfo(bytes.NewReader(src), o)
+	if err != nil {
+		return nil, err
+	}
+	inv, err := newInvocation(script, op, args)
+	if err != nil {
+		return nil, err
+	}
+	// A contract compiled for a single trigger is invoked with it.
+	if o.Trigger != "" {
+		inv.env.Trigger = vm.Triggers[o.Trigger]
+	}
+	return &debugger{
+		invocation:  inv,
+		info:        info,
+		breakpoints: map[int]bool{},
+		source:      map[string][]string{"": strings.Split(string(src), "\n")},
+		out:         os.Stdout,
+	}, nil
+}
+
+// parseArg parses an argument given on the command line. The type of the
+// argument can be given with an int:, string:, hex: or bool: prefix, else
+// it is an integer if it parses as one and a string if not.
+func parseArg(s string) (vm.StackItem, error) {
+	i := strings.Index(s, ":")
+	if i < 0 {
+		if n, ok := new(big.Int).SetString(s, 10); ok {
+			return vm.NewInteger(n), nil
+		}
+		return vm.NewByteArray([]byte(s)), nil
+	}
+
+	typ, value := s[:i], s[i+1:]
+	switch typ {
+	case "int":
+		n, ok := new(big.Int).SetString(value, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer argument %q", value)
+		}
+		return vm.NewInteger(n), nil
+	case "string":
+		return vm.NewByteArray([]byte(value)), nil
+	case "hex":
+		b, err := hexDecode(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex argument %q: %v", value, err)
+		}
+		return vm.NewByteArray(b), nil
+	case "bool":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bool argument %q", value)
+		}
+		return vm.NewBoolean(b), nil
+	default:
+		return vm.NewByteArray([]byte(s)), nil
+	}
+}
+
+// parseScriptHash parses a NEO address or a hex encoded script hash.
+func parseScriptHash(s string) (util.Uint160, error) {
+	if hash, err := crypto.Uint160DecodeAddress(s); err == nil {
+		return hash, nil
+	}
+	b, err := hexDecode(s)
+	if err != nil {
+		return util.Uint160{}, fmt.Errorf("%q is not an address or a script hash", s)
+	}
+	return util.Uint160DecodeBytes(b)
+}
+
+func hexDecode(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(s, "0x"))
+}
+
+// run reads commands from r until the input ends or the user quits.
+func (d *debugger) run(r io.Reader, w io.Writer) {
+	d.out = w
+	scanner := bufio.NewScanner(r)
+	fmt.Fprintln(w, "Type help for a list of commands.")
+	d.where()
+	for {
+		fmt.Fprint(w, "(debug) ")
+		if !scanner.Scan() {
+			fmt.Fprintln(w)
+			return
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "quit" || fields[0] == "q" {
+			return
+		}
+		if err := d.command(fields[0], fields[1:]); err != nil {
+			fmt.Fprintln(w, err)
+		}
+	}
+}
+
+func (d *debugger) command(cmd string, args []string) error {
+	switch cmd {
+	case "break", "b":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: break <line>, break <file>:<line> or break @<offset>")
+		}
+		return d.setBreakpoint(args[0])
+	case "delete":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: delete <offset>")
+		}
+		offset, err := strconv.Atoi(strings.TrimPrefix(args[0], "@"))
+		if err != nil || !d.breakpoints[offset] {
+			return fmt.Errorf("no breakpoint at offset %s", args[0])
+		}
+		delete(d.breakpoints, offset)
+	case "breakpoints":
+		offsets := make([]int, 0, len(d.breakpoints))
+		for offset := range d.breakpoints {
+			offsets = append(offsets, offset)
+		}
+		sort.Ints(offsets)
+		for _, offset := range offsets {
+			fmt.Fprintf(d.out, "@%d\t%s\n", offset, d.position(offset))
+		}
+	case "step", "s":
+		d.resume(d.stepStatement(false))
+	case "next", "n":
+		d.resume(d.stepStatement(true))
+	case "stepi", "si":
+		d.resume(d.vm.Step())
+	case "continue", "c":
+		d.resume(d.continueUntilBreakpoint())
+	case "where", "w":
+		d.where()
+	case "stack":
+		printItems(d.out, d.vm.Estack().Items())
+	case "altstack":
+		printItems(d.out, d.vm.Astack().Items())
+	case "locals":
+		return d.locals()
+	case "storage":
+		d.storage()
+	case "notifications":
+		for _, n := range d.env.Notifications {
+			fmt.Fprintf(d.out, "%s\t%s\n", n.ScriptHash, formatItem(n.Item))
+		}
+	case "help", "h":
+		fmt.Fprintln(d.out, debugHelp)
+	default:
+		return fmt.Errorf("unknown command %q, type help for a list of commands", cmd)
+	}
+	return nil
+}
+
+// setBreakpoint sets breakpoints on a line or on a bytecode offset.
+func (d *debugger) setBreakpoint(arg string) error {
+	if strings.HasPrefix(arg, "@") {
+		offset, err := strconv.Atoi(arg[1:])
+		if err != nil || offset < 0 || offset >= len(d.script) {
+			return fmt.Errorf("invalid offset %s", arg)
+		}
+		d.breakpoints[offset] = true
+		fmt.Fprintf(d.out, "breakpoint set at @%d\n", offset)
+		return nil
+	}
+
+	file, line := "", arg
+	if i := strings.LastIndex(arg, ":"); i >= 0 {
+		file, line = arg[:i], arg[i+1:]
+	}
+	n, err := strconv.Atoi(line)
+	if err != nil {
+		return fmt.Errorf("invalid line %s", arg)
+	}
+	offsets := d.info.Offsets(file, n)
+	if len(offsets) == 0 {
+		return fmt.Errorf("no code on line %s", arg)
+	}
+	for _, offset := range offsets {
+		d.breakpoints[offset] = true
+		fmt.Fprintf(d.out, "breakpoint set at @%d\n", offset)
+	}
+	return nil
+}
+
+// inContract reports whether the VM is executing the contract that is
+// debugged, rather than a contract it called.
+func (d *debugger) inContract() bool {
+	ctx := d.vm.Context()
+	return ctx != nil && ctx.ScriptHash() == d.hash
+}
+
+// atStatement reports whether the VM is at the start of a statement of the contract.
+func (d *debugger) atStatement() bool {
+	if !d.inContract() {
+		return false
+	}
+	ip := d.vm.Context().IP()
+	sp, ok := d.info.SequencePoint(ip)
+	return ok && sp.Offset == ip
+}
+
+// stepStatement executes instructions until the start of a statement is
+// reached. If over is set, statements of the functions that are called are
+// stepped over.
+func (d *debugger) stepStatement(over bool) error {
+	depth := d.vm.Depth()
+	for {
+		if err := d.vm.Step(); err != nil || d.vm.State() != vm.NoneState {
+			return err
+		}
+		if d.atStatement() && (!over || d.vm.Depth() <= depth) {
+			return nil
+		}
+	}
+}
+
+func (d *debugger) continueUntilBreakpoint() error {
+	for {
+		if err := d.vm.Step(); err != nil || d.vm.State() != vm.NoneState {
+			return err
+		}
+		if d.inContract() && d.breakpoints[d.vm.Context().IP()] {
+			fmt.Fprintf(d.out, "breakpoint at @%d\n", d.vm.Context().IP())
+			return nil
+		}
+	}
+}
+
+// resume reports the state of the VM after it was resumed.
+func (d *debugger) resume(err error) {
+	switch d.vm.State() {
+	case vm.FaultState:
+		fmt.Fprintf(d.out, "FAULT: %v\n", err)
+	case vm.HaltState:
+		fmt.Fprintln(d.out, "HALT, result:")
+		printItems(d.out, d.vm.Estack().Items())
+	default:
+		d.where()
+	}
+}
+
+// where prints the current offset along with the source line.
+func (d *debugger) where() {
+	if d.vm.State() != vm.NoneState {
+		fmt.Fprintf(d.out, "the VM is in the %s state\n", d.vm.State())
+		return
+	}
+	ctx := d.vm.Context()
+	if !d.inContract() {
+		fmt.Fprintf(d.out, "@%d in contract %s\n", ctx.IP(), ctx.ScriptHash())
+		return
+	}
+	fmt.Fprintf(d.out, "@%d %s\n", ctx.IP(), d.position(ctx.IP()))
+}
+
+// position returns the function and the source line of an offset.
+func (d *debugger) position(offset int) string {
+	var pos string
+	if f := d.info.Function(offset); f != nil {
+		pos = f.Name
+	}
+	sp, ok := d.info.SequencePoint(offset)
+	if !ok {
+		return pos
+	}
+	file := sp.File
+	if file == "" {
+		file = "contract"
+	}
+	pos = fmt.Sprintf("%s %s:%d", pos, file, sp.Line)
+	if line := d.sourceLine(sp.File, sp.Line); line != "" {
+		pos += "\n\t" + line
+	}
+	return pos
+}
+
+func (d *debugger) sourceLine(file string, line int) string {
+	lines, ok := d.source[file]
+	if !ok {
+		b, _ := ioutil.ReadFile(file)
+		lines = strings.Split(string(b), "\n")
+		d.source[file] = lines
+	}
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[line-1])
+}
+
+// locals prints the local variables of the function that is executing,
+// which are kept in an array on top of the alt stack.
+func (d *debugger) locals() error {
+	if !d.inContract() {
+		return fmt.Errorf("not executing the contract")
+	}
+	ip := d.vm.Context().IP()
+	f := d.info.Function(ip)
+	if f == nil {
+		return fmt.Errorf("no function at @%d", ip)
+	}
+	// The locals are not set up before the first statement of the function.
+	if sp, ok := d.info.SequencePoint(ip); !ok || sp.Offset < f.Start || d.vm.Astack().Len() == 0 {
+		return fmt.Errorf("the locals of %s are not initialized yet", f.Name)
+	}
+	items, ok := d.vm.Astack().Peek(0).Value().([]vm.StackItem)
+	if !ok {
+		return fmt.Errorf("the locals of %s are not on the alt stack", f.Name)
+	}
+
+	names := make([]string, 0, len(f.Locals))
+	for name := range f.Locals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		i := f.Locals[name]
+		if i < len(items) {
+			fmt.Fprintf(d.out, "%s = %s\n", name, formatItem(items[i]))
+		}
+	}
+	return nil
+}
+
+func (d *debugger) storage() {
+	items := d.env.Storage[d.hash]
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(d.out, "%s = %s\n", formatBytes([]byte(key)), formatBytes(items[key].Value))
+	}
+}
+
+func printItems(w io.Writer, items []vm.StackItem) {
+	for i, item := range items {
+		fmt.Fprintf(w, "%d: %s\n", i, formatItem(item))
+	}
+}
+
+// formatItem formats a stack item for display, byte arrays holding
+// printable text are shown as a string. Arrays, structs and maps that hold
+// themselves are shown as a reference, see formatRef.
+func formatItem(item vm.StackItem) string {
+	return formatItemIn(item, nil)
+}
+
+// formatItemIn formats an item that is held by the given parents, the one
+// holding it directly last.
+func formatItemIn(item vm.StackItem, parents []vm.StackItem) string {
+	switch item.(type) {
+	case *vm.Array, *vm.Struct, *vm.Map:
+		for i := len(parents) - 1; i >= 0; i-- {
+			if parents[i] == item {
+				return formatRef(len(parents) - i)
+			}
+		}
+		parents = append(parents, item)
+	}
+
+	switch t := item.(type) {
+	case nil:
+		return "nil"
+	case *vm.ByteArray:
+		return formatBytes(t.Bytes())
+	case *vm.Array, *vm.Struct:
+		items := t.Value().([]vm.StackItem)
+		s := make([]string, len(items))
+		for i, item := range items {
+			s[i] = formatItemIn(item, parents)
+		}
+		return "[" + strings.Join(s, ", ") + "]"
+	case *vm.Map:
+		s := make([]string, 0, t.Len())
+		for _, key := range t.Keys() {
+			value, _ := t.Get(key)
+			s = append(s, formatItemIn(key, parents)+": "+formatItemIn(value, parents))
+		}
+		return "{" + strings.Join(s, ", ") + "}"
+	default:
+		return item.String()
+	}
+}
+
+func formatBytes(b []byte) string {
+	if len(b) > 0 && utf8.Valid(b) && strings.IndexFunc(string(b), func(r rune) bool {
+		return !unicode.IsPrint(r)
+	}) < 0 {
+		return strconv.Quote(string(b))
+	}
+	return fmt.Sprintf("0x%x", b)
+}
+
+// formatRef formats an array, struct or map that holds itself, as the number
+// of levels up to where it holds itself.
+func formatRef(levels int) string {
+	return "^" + strconv.Itoa(levels)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/CityOfZion/neo-storm/compiler"
+	"github.com/CityOfZion/neo-storm/vm"
+)
+
+func TestDebugger(t *testing.T) {
+	src := `package foo
+	func Main(op string, args []interface{}) int {
+		if op == "double" {
+			n := args[0].(int)
+			n = twice(n)
+			return n
+		}
+		return 0
+	}
+
+	func twice(x int) int {
+		y := x * 2
+		return y
+	}`
+
+	d, err := newDebugger([]byte(src), &compiler.Options{}, "double", []string{"21"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	d.run(strings.NewReader("break 5\ncontinue\nlocals\nstep\nnext\nlocals\ncontinue\n"), out)
+
+	for _, expect := range []string{
+		"breakpoint at @",
+		"n = 21\n",
+		"twice contract:12",
+		"x = 21\ny = 42\n",
+		"HALT, result:\n0: 42\n",
+	} {
+		if !strings.Contains(out.String(), expect) {
+			t.Fatalf("expected the output to contain %q, got:\n%s", expect, out)
+		}
+	}
+}
+
+func TestDebuggerOptions(t *testing.T) {
+	src := `package foo
+	import (
+		"github.com/CityOfZion/neo-storm/interop/crypto"
+		"github.com/CityOfZion/neo-storm/interop/runtime"
+	)
+	func Main(msg, sig, pubkey []byte) int {
+		if runtime.GetTrigger() == runtime.Verification() {
+			return 1
+		}
+		if crypto.VerifySignature(msg, sig, pubkey) {
+			return 2
+		}
+		return 3
+	}`
+
+	// The contract is invoked with the trigger it is compiled for.
+	args := []string{"string:msg", "hex:00", "hex:00"}
+	d, err := newDebugger([]byte(src), &compiler.Options{Trigger: "verification"}, "", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	d.run(strings.NewReader("continue\n"), out)
+	if !strings.Contains(out.String(), "HALT, result:\n0: 1\n") {
+		t.Fatalf("expected the verification result, got:\n%s", out)
+	}
+
+	_, err = newDebugger([]byte(src), &compiler.Options{VMVersion: "2.0"}, "", args)
+	if err == nil || !strings.Contains(err.Error(), "VERIFY is not available in version 2.0") {
+		t.Fatalf("expected VERIFY to be refused in version 2.0, got %v", err)
+	}
+}
+
+func TestParseArg(t *testing.T) {
+	var cases = []struct {
+		arg    string
+		expect string
+	}{
+		{"42", "42"},
+		{"int:-1", "-1"},
+		{"hello", `"hello"`},
+		{"string:12", `"12"`},
+		{"hex:0x0102", "0x0102"},
+		{"bool:true", "true"},
+	}
+	for _, c := range cases {
+		item, err := parseArg(c.arg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := formatItem(item); s != c.expect {
+			t.Errorf("%s: expected %s, got %s", c.arg, c.expect, s)
+		}
+	}
+}
+
+func TestFormatItemRef(t *testing.T) {
+	items := []vm.StackItem{vm.NewIntegerInt64(1), nil}
+	arr := vm.NewArray(items)
+	items[1] = arr
+	m := vm.NewMap()
+	m.Set(vm.NewByteArray([]byte("self")), m)
+	m.Set(vm.NewByteArray([]byte("arr")), vm.NewArray([]vm.StackItem{arr}))
+
+	if s := formatItem(arr); s != "[1, ^1]" {
+		t.Errorf("expected the array to refer to itself, got %s", s)
+	}
+	if s := formatItem(m); s != `{"self": ^1, "arr": [[1, ^1]]}` {
+		t.Errorf("expected the map to refer to itself, got %s", s)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/CityOfZion/neo-storm/compiler"
+	"github.com/CityOfZion/neo-storm/vm"
+	"github.com/urfave/cli"
+)
+
+func inspect(ctx *cli.Context) error {
+	src := ctx.String("in")
+	if len(src) == 0 {
+		return cli.NewExitError(errNoInput, 1)
+	}
+	script, err := readScript(src, ctx.Bool("hex"))
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	write := writeInstructions
+	if ctx.Bool("asm") {
+		write = writeAssembly
+	}
+	if err := write(os.Stdout, script); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	return nil
+}
+
+// readScript returns the bytecode of a contract. Go files are compiled,
+// other files hold the bytecode itself, hex encoded if isHex is set.
+func readScript(path string, isHex bool) ([]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(path, ".go") {
+		return compiler.Compile(bytes.NewReader(b), &compiler.Options{})
+	}
+	if isHex {
+		return hexDecode(strings.TrimSpace(string(b)))
+	}
+	return b, nil
+}
+
+// writeInstructions writes a table of the instructions of the script. The
+// table ends with the error if the script could not be decoded completely.
+func writeInstructions(w io.Writer, script []byte) error {
+	instrs, err := vm.Disassemble(script)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 4, ' ', 0)
+	fmt.Fprintln(tw, "OFFSET\tOPCODE\tOPERAND\t")
+	for _, instr := range instrs {
+		operand := instr.OperandString()
+		if instr.IsPush() && len(instr.Operand) > 0 {
+			if s := formatBytes(instr.Operand); s[0] == '"' {
+				operand += " " + s
+			}
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t\n", instr.Offset, instr.Name(), operand)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	return err
+}
+
+// writeAssembly writes the instructions of the script in the text format
+// of vm.Assemble.
+func writeAssembly(w io.Writer, script []byte) error {
+	instrs, err := vm.Disassemble(script)
+	for _, instr := range instrs {
+		fmt.Fprintln(w, instr)
+	}
+	return err
+}
@@ -55,7 +55,19 @@ func main() {
 				},
 				cli.BoolFlag{
 					Name:  "debug, d",
-					Usage: "compile the contract in debug mode for additional compile information",
+					Usage: "also write the debug information of the contract to a .debug.json file",
+				},
+				cli.BoolFlag{
+					Name:  "abi",
+					Usage: "also write the ABI of the contract to a .abi.json file",
+				},
+				cli.StringFlag{
+					Name:  "trigger, t",
+					Usage: "compile the contract for a single trigger (verification, verificationR, application or applicationR)",
+				},
+				cli.StringFlag{
+					Name:  "vm-version",
+					Usage: "version of the VM to compile the contract for (2.0, 2.1 or 2.3), defaults to the latest",
 				},
 			},
 		},
@@ -85,6 +97,68 @@ func main() {
 				},
 			},
 		},
+		{
+			Name:      "debug",
+			Usage:     "debug a smart contract on the local VM",
+			ArgsUsage: "[arguments...]",
+			Description: `Compiles the contract and runs it step by step. The arguments follow the
+   flags and can be prefixed with their type: int:, string:, hex: or bool:.`,
+			Action: contractDebug,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "in, i",
+					Usage: "input file of the contract",
+				},
+				cli.StringFlag{
+					Name:  "op",
+					Usage: "operation to invoke, the arguments are passed as the args array of Main(op string, args []interface{})",
+				},
+				cli.StringSliceFlag{
+					Name:  "witness, w",
+					Usage: "address or script hash of which the witness is checked successfully, can be repeated",
+				},
+				cli.StringFlag{
+					Name:  "trigger, t",
+					Usage: "compile the contract for a single trigger and invoke it with that trigger (verification, verificationR, application or applicationR)",
+				},
+				cli.StringFlag{
+					Name:  "vm-version",
+					Usage: "version of the VM to compile the contract for (2.0, 2.1 or 2.3), defaults to the latest",
+				},
+			},
+		},
+		{
+			Name:      "trace",
+			Usage:     "trace the execution of a smart contract on the local VM",
+			ArgsUsage: "[arguments...]",
+			Description: `Runs the contract and shows every instruction it executes with the GAS
+   consumed and the evaluation stack after it. A .go file is compiled and the
+   trace shows the source lines, any other file is read as compiled bytecode.
+   The arguments are passed like the ones of the debug command.`,
+			Action: contractTrace,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "in, i",
+					Usage: "input file of the contract",
+				},
+				cli.StringFlag{
+					Name:  "op",
+					Usage: "operation to invoke, the arguments are passed as the args array of Main(op string, args []interface{})",
+				},
+				cli.StringSliceFlag{
+					Name:  "witness, w",
+					Usage: "address or script hash of which the witness is checked successfully, can be repeated",
+				},
+				cli.BoolFlag{
+					Name:  "json",
+					Usage: "write the trace as lines of JSON instead of a table",
+				},
+				cli.StringFlag{
+					Name:  "render, r",
+					Usage: "show a trace that was written with --json as a table instead of running a contract",
+				},
+			},
+		},
 		{
 			Name:   "inspect",
 			Usage:  "creates a user readable dump of the program instructions",
@@ -92,7 +166,30 @@ func main() {
 			Flags: []cli.Flag{
 				cli.StringFlag{
 					Name:  "in, i",
-					Usage: "input file of the program",
+					Usage: "input file of the program, a .go file is compiled and any other file is read as compiled bytecode",
+				},
+				cli.BoolFlag{
+					Name:  "hex",
+					Usage: "read the bytecode from the input file as a hex string",
+				},
+				cli.BoolFlag{
+					Name:  "asm",
+					Usage: "write the instructions in the format of the asm command",
+				},
+			},
+		},
+		{
+			Name:   "asm",
+			Usage:  "assemble a script from its text format to an .avm file",
+			Action: contractAssemble,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "in, i",
+					Usage: "input file with the instructions of the script",
+				},
+				cli.StringFlag{
+					Name:  "out, o",
+					Usage: "output destination of the script, defaults to the input file with the .avm extension",
 				},
 			},
 		},
@@ -146,8 +243,11 @@ func contractCompile(ctx *cli.Context) error {
 	}
 
 	o := &compiler.Options{
-		Outfile: ctx.String("out"),
-		Debug:   ctx.Bool("debug"),
+		Outfile:   ctx.String("out"),
+		Debug:     ctx.Bool("debug"),
+		ABI:       ctx.Bool("abi"),
+		Trigger:   ctx.String("trigger"),
+		VMVersion: ctx.String("vm-version"),
 	}
 
 	if err := compiler.CompileAndSave(src, o); err != nil {
@@ -245,12 +345,3 @@ func parseContractDetails() ContractDetails {
 
 	return details
 }
-
-func inspect(ctx *cli.Context) error {
-	src := ctx.String("in")
-	if len(src) == 0 {
-		return cli.NewExitError(errNoInput, 1)
-	}
-	compiler.CompileAndInspect(src)
-	return nil
-}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/CityOfZion/neo-go/pkg/util"
+	"github.com/CityOfZion/neo-storm/compiler"
+	"github.com/CityOfZion/neo-storm/vm"
+	"github.com/urfave/cli"
+)
+
+func contractTrace(ctx *cli.Context) error {
+	if path := ctx.String("render"); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		defer f.Close()
+		if err := renderTrace(f, os.Stdout); err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		return nil
+	}
+
+	src := ctx.String("in")
+	if len(src) == 0 {
+		return cli.NewExitError(errNoInput, 1)
+	}
+	trace := new(bytes.Buffer)
+	if err := traceContract(trace, src, ctx.String("op"), ctx.Args(), ctx.StringSlice("witness")); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	var err error
+	if ctx.Bool("json") {
+		_, err = io.Copy(os.Stdout, trace)
+	} else {
+		err = renderTrace(trace, os.Stdout)
+	}
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	return nil
+}
+
+// traceContract invokes the contract on the local VM and writes the trace
+// of the execution to w. A Go file is compiled and the trace holds the
+// source positions of its instructions, other files hold the bytecode.
+func traceContract(w io.Writer, path, op string, args, witnesses []string) error {
+	var (
+		script []byte
+		info   *compiler.DebugInfo
+	)
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(path, ".go") {
+		script, info, err = compiler.CompileWithDebugInfo(bytes.NewReader(b), &compiler.Options{})
+		if err != nil {
+			return err
+		}
+	} else {
+		script = b
+	}
+
+	inv, err := newInvocation(script, op, args)
+	if err != nil {
+		return err
+	}
+	if err := inv.addWitnesses(witnesses); err != nil {
+		return err
+	}
+
+	var source func(util.Uint160, int) string
+	if info != nil {
+		source = func(script util.Uint160, offset int) string {
+			sp, ok := info.SequencePoint(offset)
+			if script != inv.hash || !ok {
+				return ""
+			}
+			// Only the names of the files are used, so the traces of
+			// contracts built on different machines can be compared.
+			file := filepath.Base(sp.File)
+			if sp.File == "" {
+				file = filepath.Base(path)
+			}
+			return fmt.Sprintf("%s:%d", file, sp.Line)
+		}
+	}
+	inv.vm.SetTrace(w, source)
+	// A fault is part of the trace.
+	inv.vm.Run()
+	return nil
+}
+
+// renderTrace writes a trace, read as lines of JSON, as a table.
+func renderTrace(r io.Reader, w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STEP\tDEPTH\tOFFSET\tOPCODE\tGAS\tSOURCE\tSTACK")
+
+	var last vm.TraceStep
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var step vm.TraceStep
+		if err := json.Unmarshal(scanner.Bytes(), &step); err != nil {
+			return fmt.Errorf("invalid trace step: %v", err)
+		}
+		stack := make([]string, len(step.Stack))
+		for i, item := range step.Stack {
+			stack[i] = formatTraceItem(item)
+		}
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%s\t%s\t%s\t%s\n", step.Step, step.Depth, step.Offset,
+			step.Opcode, step.GasConsumed, step.Source, "["+strings.Join(stack, ", ")+"]")
+		last = step
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if last.Error != "" {
+		fmt.Fprintf(w, "%s: %s\n", last.State, last.Error)
+	} else if last.State != "" {
+		fmt.Fprintln(w, last.State)
+	}
+	return nil
+}
+
+// formatTraceItem formats an item of a trace like formatItem does.
+func formatTraceItem(item vm.TraceItem) string {
+	if item.Ref > 0 {
+		return formatRef(item.Ref)
+	}
+	switch item.Type {
+	case "ByteArray":
+		b, _ := hex.DecodeString(item.Value)
+		return formatBytes(b)
+	case "Array", "Struct":
+		s := make([]string, len(item.Items))
+		for i, item := range item.Items {
+			s[i] = formatTraceItem(item)
+		}
+		return "[" + strings.Join(s, ", ") + "]"
+	case "Map":
+		s := make([]string, len(item.Entries))
+		for i, e := range item.Entries {
+			s[i] = formatTraceItem(e.Key) + ": " + formatTraceItem(e.Value)
+		}
+		return "{" + strings.Join(s, ", ") + "}"
+	default:
+		return item.Value
+	}
+}
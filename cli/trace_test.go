@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTraceContract(t *testing.T) {
+	src := `package foo
+	func Main(op string, args []interface{}) int {
+		if op == "double" {
+			n := args[0].(int)
+			return n * 2
+		}
+		return 0
+	}`
+
+	dir, err := ioutil.TempDir("", "trace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "contract.go")
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	trace := new(bytes.Buffer)
+	if err := traceContract(trace, path, "double", []string{"21"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	if err := renderTrace(trace, out); err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		"STEP  DEPTH  OFFSET  OPCODE",
+		"contract.go:5",
+		"[42]",
+		"\nHALT\n",
+	} {
+		if !strings.Contains(out.String(), expect) {
+			t.Fatalf("expected the trace to contain %q, got:\n%s", expect, out)
+		}
+	}
+}
+
+func TestRenderTraceRef(t *testing.T) {
+	trace := `{"step":1,"offset":4,"opcode":"APPEND","depth":1,"stack":[{"type":"Array","items":[{"type":"Integer","value":"1"},{"type":"Array","ref":1}]}],"gas":"0","state":"NONE"}`
+	out := new(bytes.Buffer)
+	if err := renderTrace(strings.NewReader(trace), out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "[[1, ^1]]") {
+		t.Fatalf("expected the array to refer to itself, got:\n%s", out)
+	}
+}
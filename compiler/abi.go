@@ -0,0 +1,132 @@
+package compiler
+
+import (
+	"go/ast"
+	"go/types"
+	"log"
+	"strings"
+
+	"github.com/CityOfZion/neo-go/pkg/util"
+)
+
+const interopPrefix = "github.com/CityOfZion/neo-storm/interop/"
+
+// ABI describes the interface of a compiled smart contract as specified
+// by NEP-3.
+type ABI struct {
+	Hash       util.Uint160 `json:"hash"`
+	EntryPoint string       `json:"entrypoint"`
+	Functions  []Function   `json:"functions"`
+	Events     []Event      `json:"events"`
+
+	// DynamicInvoke is true if the contract calls other contracts of which
+	// the script hash is only known at runtime. These contracts need to be
+	// deployed with dynamic invoke enabled.
+	DynamicInvoke bool `json:"dynamicinvoke"`
+}
+
+// Function describes a method of the contract.
+type Function struct {
+	Name       string      `json:"name"`
+	Parameters []Parameter `json:"parameters"`
+	ReturnType string      `json:"returntype"`
+}
+
+// Event describes a notification the contract can emit.
+type Event struct {
+	Name       string      `json:"name"`
+	Parameters []Parameter `json:"parameters"`
+}
+
+// Parameter describes a parameter of a function or event.
+type Parameter struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// generateABI generates the ABI of the compiled program.
+func generateABI(info *buildInfo, script []byte) *ABI {
+	pkg := info.program.Package(info.initialPackage)
+	main, _ := resolveEntryPoint(mainIdent, pkg)
+
+	hash, err := util.Uint160FromScript(script)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	events := info.events
+	if events == nil {
+		events = []Event{}
+	}
+
+	return &ABI{
+		Hash:          hash,
+		EntryPoint:    mainIdent,
+		Functions:     []Function{abiFunction(&pkg.Info, main)},
+		Events:        events,
+		DynamicInvoke: info.dynamicInvoke,
+	}
+}
+
+func abiFunction(typeInfo *types.Info, decl *ast.FuncDecl) Function {
+	sig := typeInfo.Defs[decl.Name].Type().(*types.Signature)
+	fun := Function{
+		Name:       decl.Name.Name,
+		Parameters: abiParameters(sig.Params()),
+		ReturnType: "Void",
+	}
+	if sig.Results().Len() > 0 {
+		fun.ReturnType = abiType(sig.Results().At(0).Type())
+	}
+	return fun
+}
+
+func abiParameters(params *types.Tuple) []Parameter {
+	list := make([]Parameter, params.Len())
+	for i := 0; i < params.Len(); i++ {
+		list[i] = Parameter{
+			Name: params.At(i).Name(),
+			Type: abiType(params.At(i).Type()),
+		}
+	}
+	return list
+}
+
+// abiType returns the NEP-3 parameter type of the given Go type.
+func abiType(typ types.Type) string {
+	if named, ok := typ.(*types.Named); ok {
+		if pkg := named.Obj().Pkg(); pkg != nil && strings.HasPrefix(pkg.Path(), interopPrefix) {
+			return "InteropInterface"
+		}
+	}
+	if n, ok := byteArrayLen(typ); ok {
+		switch n {
+		case 20:
+			return "Hash160"
+		case 32:
+			return "Hash256"
+		case 33:
+			return "PublicKey"
+		case 64:
+			return "Signature"
+		}
+		return "ByteArray"
+	}
+	if isByteType(typ) {
+		return "ByteArray"
+	}
+	switch t := typ.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case t.Info()&types.IsBoolean != 0:
+			return "Boolean"
+		case t.Info()&types.IsInteger != 0:
+			return "Integer"
+		case t.Info()&types.IsString != 0:
+			return "String"
+		}
+	case *types.Slice, *types.Array, *types.Struct:
+		return "Array"
+	}
+	return "ByteArray"
+}
@@ -0,0 +1,206 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/CityOfZion/neo-storm/vm"
+)
+
+func TestABIDynamicInvoke(t *testing.T) {
+	src := `package foo
+	import "github.com/CityOfZion/neo-storm/interop/contract"
+	func Main(op string, hash [20]byte) interface{} {
+		if op == "static" {
+			return contract.Call([20]byte([]byte("abcdefghijklmnopqrst")), "name")
+		}
+		return contract.Call(hash, op, 1, 2)
+	}`
+	_, abi, err := compile(strings.NewReader(src), &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !abi.DynamicInvoke {
+		t.Fatal("expected the contract to need dynamic invoke")
+	}
+
+	src = strings.Replace(src, "contract.Call(hash, op, 1, 2)", "nil", 1)
+	_, abi, err = compile(strings.NewReader(src), &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if abi.DynamicInvoke {
+		t.Fatal("expected the contract to not need dynamic invoke")
+	}
+}
+
+func TestABIEntryPoint(t *testing.T) {
+	src := `package foo
+	func Main(op string, args []interface{}, owner [20]byte, amount int) bool {
+		return true
+	}`
+	_, abi, err := compile(strings.NewReader(src), &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if abi.EntryPoint != "Main" || len(abi.Functions) != 1 {
+		t.Fatalf("unexpected entry point %s with %d functions", abi.EntryPoint, len(abi.Functions))
+	}
+	fun := abi.Functions[0]
+	if fun.ReturnType != "Boolean" {
+		t.Fatalf("expected return type Boolean got %s", fun.ReturnType)
+	}
+	expected := []Parameter{
+		{Name: "op", Type: "String"},
+		{Name: "args", Type: "Array"},
+		{Name: "owner", Type: "Hash160"},
+		{Name: "amount", Type: "Integer"},
+	}
+	for i, param := range fun.Parameters {
+		if param != expected[i] {
+			t.Fatalf("expected parameter %v got %v", expected[i], param)
+		}
+	}
+}
+
+func TestAppCallStatic(t *testing.T) {
+	src := `package foo
+	import (
+		"github.com/CityOfZion/neo-storm/interop/contract"
+		"github.com/CityOfZion/neo-storm/interop/util"
+	)
+	func Main() interface{} {
+		return contract.Call([20]byte(util.FromAddress("AK2nJJpJr6o664CWJKi1QRXjqeic2zRp8y")), "name", 1)
+	}`
+	script, _, err := compile(strings.NewReader(src), &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The method is moved on top of the argument and the script hash of the
+	// address is the operand, in little endian like it is pushed for
+	// runtime.CheckWitness.
+	hash, _ := hex.DecodeString("23ba2703c53263e8d6e522dc32203339dcd8eee9")
+	expected := append([]byte{byte(vm.SWAP), byte(vm.APPCALL)}, hash...)
+	if !bytes.Contains(script, expected) {
+		t.Fatalf("expected %x in %x", expected, script)
+	}
+}
+
+func TestAppCallDynamic(t *testing.T) {
+	src := `package foo
+	import "github.com/CityOfZion/neo-storm/interop/contract"
+	func Main(hash [20]byte) interface{} {
+		return contract.Call(hash, "name", 1)
+	}`
+	script, _, err := compile(strings.NewReader(src), &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The hash is the first of the three arguments, it is moved on top of
+	// the method and the other argument, and the operand is zero.
+	expected := append([]byte{byte(vm.XSWAP), byte(vm.APPCALL)}, make([]byte, 20)...)
+	if !bytes.Contains(script, expected) {
+		t.Fatalf("expected %x in %x", expected, script)
+	}
+}
+
+// Operands of instructions that look like jumps are not taken for jumps when
+// the labels are replaced with offsets.
+func TestWriteJumpsOperands(t *testing.T) {
+	src := `package foo
+	import "github.com/CityOfZion/neo-storm/interop/contract"
+	func Main(b bool) interface{} {
+		data := []byte{0x62, 0x01, 0x00, 0x63, 0x02, 0x00}
+		if b {
+			return data
+		}
+		return contract.Call([20]byte([]byte("bcdbcdbcdbcdbcdbcdbc")), "name")
+	}`
+	script, _, err := compile(strings.NewReader(src), &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte{0x62, 0x01, 0x00, 0x63, 0x02, 0x00}
+	if !bytes.Contains(script, data) {
+		t.Fatalf("expected the data %x to be left untouched in %x", data, script)
+	}
+	hash := append([]byte{byte(vm.APPCALL)}, "bcdbcdbcdbcdbcdbcdbc"...)
+	if !bytes.Contains(script, hash) {
+		t.Fatalf("expected the script hash to be left untouched in %x", script)
+	}
+}
+
+func TestABIEvents(t *testing.T) {
+	src := `package foo
+	// OnTransfer is emitted when tokens are transferred.
+	//
+	//neo:event transfer
+	func OnTransfer(from, to [20]byte, amount int) {}
+
+	// OnUnused is never emitted, but declared in the ABI nonetheless.
+	//
+	//neo:event unused
+	func OnUnused() {}
+
+	func Main(from, to [20]byte) {
+		OnTransfer(from, to, 1)
+		OnTransfer(to, from, 2)
+	}`
+	_, abi, err := compile(strings.NewReader(src), &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(abi.Events) != 2 || abi.Events[0].Name != "transfer" || abi.Events[1].Name != "unused" {
+		t.Fatalf("expected the transfer and unused events got %v", abi.Events)
+	}
+	if len(abi.Events[1].Parameters) != 0 {
+		t.Fatalf("expected no parameters for the unused event got %v", abi.Events[1].Parameters)
+	}
+	expected := []Parameter{
+		{Name: "from", Type: "Hash160"},
+		{Name: "to", Type: "Hash160"},
+		{Name: "amount", Type: "Integer"},
+	}
+	for i, param := range abi.Events[0].Parameters {
+		if param != expected[i] {
+			t.Fatalf("expected parameter %v got %v", expected[i], param)
+		}
+	}
+}
+
+// The script hash of a dynamic APPCALL is taken from the stack.
+func TestAppCallDynamicRun(t *testing.T) {
+	script, _, err := compile(strings.NewReader(`package foo
+	import "github.com/CityOfZion/neo-storm/interop/contract"
+	func Main(hash [20]byte) interface{} {
+		return contract.Call(hash, "name", 1)
+	}`), &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	callee, _, err := compile(strings.NewReader(`package bar
+	func Main(op string, args []interface{}) int {
+		if op == "name" {
+			return args[0].(int) + 10
+		}
+		return 0
+	}`), &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := vm.NewEnvironment()
+	hash := env.Chain.AddContract(callee, false, false)
+	v := vm.New()
+	env.Attach(v)
+	v.Estack().Push(vm.NewByteArray(hash.Bytes()))
+	v.LoadScript(script)
+	if err := v.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if res := v.Estack().Pop().BigInt().Int64(); res != 11 {
+		t.Fatalf("expected 11, got %d", res)
+	}
+}
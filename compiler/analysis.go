@@ -5,7 +5,9 @@ import (
 	"go/constant"
 	"go/types"
 	"log"
+	"strings"
 
+	"github.com/CityOfZion/neo-storm/vm"
 	"golang.org/x/tools/go/loader"
 )
 
@@ -16,34 +18,14 @@ var (
 		"SHA1", "Hash256", "Hash160",
 		"FromAddress", "Equals",
 	}
-)
 
-// typeAndValueForField returns a zero initialized typeAndValue for the given type.Var.
-func typeAndValueForField(fld *types.Var) types.TypeAndValue {
-	switch t := fld.Type().(type) {
-	case *types.Basic:
-		switch t.Kind() {
-		case types.Int:
-			return types.TypeAndValue{
-				Type:  t,
-				Value: constant.MakeInt64(0),
-			}
-		case types.String:
-			return types.TypeAndValue{
-				Type:  t,
-				Value: constant.MakeString(""),
-			}
-		case types.Bool, types.UntypedBool:
-			return types.TypeAndValue{
-				Type:  t,
-				Value: constant.MakeBool(false),
-			}
-		default:
-			log.Fatalf("could not initialize struct field %s to zero, type: %s", fld.Name(), t)
-		}
+	// Functions of the interop packages that the compiler implements
+	// itself, by their qualified name, see funcName.
+	interopBuiltins = map[string]bool{
+		"github.com/CityOfZion/neo-storm/interop/storage.PutStruct": true,
+		"github.com/CityOfZion/neo-storm/interop/storage.GetStruct": true,
 	}
-	return types.TypeAndValue{}
-}
+)
 
 // countGlobals counts the global variables in the program to add
 // them with the stacksize of the function.
@@ -106,15 +88,11 @@ func resolveEntryPoint(entry string, pkg *loader.PackageInfo) (*ast.FuncDecl, *a
 	return main, file
 }
 
-// indexOfStruct will return the index of the given field inside that struct.
-// If the struct does not contain that field it will return -1.
-func indexOfStruct(strct *types.Struct, fldName string) int {
-	for i := 0; i < strct.NumFields(); i++ {
-		if strct.Field(i).Name() == fldName {
-			return i
-		}
-	}
-	return -1
+// funcName returns the name of the given function qualified with its package
+// path, methods are also qualified with their receiver type.
+// (github.com/CityOfZion/neo-storm/examples/token/nep5.Token).Transfer
+func funcName(fun *types.Func) string {
+	return fun.FullName()
 }
 
 type funcUsage map[string]bool
@@ -158,15 +136,21 @@ func analyzeFuncUsage(pkgs map[*types.Package]*loader.PackageInfo) funcUsage {
 	return usage
 }
 
-func isBuiltin(expr ast.Expr) bool {
-	var name string
-
+// builtinName returns the name of the function called by expr, without
+// the package selector.
+func builtinName(expr ast.Expr) string {
 	switch t := expr.(type) {
 	case *ast.Ident:
-		name = t.Name
+		return t.Name
 	case *ast.SelectorExpr:
-		name = t.Sel.Name
-	default:
+		return t.Sel.Name
+	}
+	return ""
+}
+
+func isBuiltin(expr ast.Expr) bool {
+	name := builtinName(expr)
+	if name == "" {
 		return false
 	}
 
@@ -178,28 +162,172 @@ func isBuiltin(expr ast.Expr) bool {
 	return false
 }
 
-func isByteArray(lit *ast.CompositeLit, tInfo *types.Info) bool {
-	if len(lit.Elts) == 0 {
+// isByteType returns true if the given type is a byte slice or a fixed
+// size byte array.
+func isByteType(typ types.Type) bool {
+	var elem types.Type
+	switch t := typ.(type) {
+	case *types.Slice:
+		elem = t.Elem()
+	case *types.Array:
+		elem = t.Elem()
+	default:
 		return false
 	}
+	t, ok := elem.Underlying().(*types.Basic)
+	return ok && t.Kind() == types.Byte
+}
 
-	typ := tInfo.Types[lit.Elts[0]].Type.Underlying()
-	switch t := typ.(type) {
+// byteArrayLen returns the length of the given fixed size byte array type,
+// like [20]byte for script hashes and [33]byte for public keys.
+func byteArrayLen(typ types.Type) (int, bool) {
+	if typ == nil {
+		return 0, false
+	}
+	t, ok := typ.Underlying().(*types.Array)
+	if !ok || !isByteType(t) {
+		return 0, false
+	}
+	return int(t.Len()), true
+}
+
+// Kinds of checks that are done at runtime for type assertions and switches.
+const (
+	// The type can not be checked.
+	checkUndecidable = iota
+	// The assertion always succeeds.
+	checkAlways
+	// The size of the byte array is checked.
+	checkSize
+)
+
+// runtimeTypeCheck returns how a type assertion to the given type is checked
+// at runtime. The VM implicitly converts byte arrays, integers and booleans
+// into each other, so assertions to these types, strings and byte slices
+// always succeed. Fixed size byte arrays are checked by their size, which
+// could be the size of an array as well, and FAULT if the value is an interop
+// interface. The VM has no way to inspect other types, like structs or
+// slices, without the risk of a FAULT.
+func runtimeTypeCheck(typ types.Type) (check int, size int) {
+	if size, ok := byteArrayLen(typ); ok {
+		return checkSize, size
+	}
+	switch t := typ.Underlying().(type) {
 	case *types.Basic:
-		switch t.Kind() {
-		case types.Byte:
-			return true
+		if t.Info()&(types.IsInteger|types.IsString|types.IsBoolean) != 0 {
+			return checkAlways, 0
+		}
+	case *types.Slice:
+		if isByteType(t) {
+			return checkAlways, 0
+		}
+	case *types.Interface:
+		if t.Empty() {
+			return checkAlways, 0
 		}
 	}
-	return false
+	return checkUndecidable, 0
+}
+
+// fieldValue returns the value of the i-th field of the given struct
+// literal, or nil if the field is not initialized by the literal.
+func fieldValue(lit *ast.CompositeLit, strct *types.Struct, i int) ast.Expr {
+	for j, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			// Struct literals without keys initialize all the fields in order.
+			if j == i {
+				return elt
+			}
+			continue
+		}
+		if kv.Key.(*ast.Ident).Name == strct.Field(i).Name() {
+			return kv.Value
+		}
+	}
+	return nil
 }
 
 func isSyscall(fun *funcScope) bool {
-	if fun.selector == nil {
+	return fun.syscall != ""
+}
+
+// isInlined returns true if calls to the given function are compiled to a
+// syscall, opcodes, a notification, a constant or the code of an interop
+// builtin instead of a call to its body.
+func isInlined(fun *funcScope) bool {
+	return isSyscall(fun) || len(fun.opcodes) > 0 || fun.event != "" || fun.value.Value != nil || fun.builtin
+}
+
+// directiveArg returns the argument of the directive with the given prefix in
+// the doc comment of the given function declaration, like the api of
+// //neo:syscall Neo.Storage.Put. It returns an empty string if the function
+// has no such directive.
+func directiveArg(decl *ast.FuncDecl, prefix string) string {
+	if decl.Doc == nil {
+		return ""
+	}
+	for _, comment := range decl.Doc.List {
+		if strings.HasPrefix(comment.Text, prefix) {
+			arg := strings.TrimSpace(strings.TrimPrefix(comment.Text, prefix))
+			if arg == "" {
+				log.Fatalf("missing argument in directive %s of function %s", strings.TrimSpace(prefix), decl.Name.Name)
+			}
+			return arg
+		}
+	}
+	return ""
+}
+
+// opcodeDirective returns the opcodes of the opcode directive in the doc
+// comment of the given function declaration.
+func opcodeDirective(decl *ast.FuncDecl) []vm.Instruction {
+	if decl.Doc == nil {
+		return nil
+	}
+	var opcodes []vm.Instruction
+	for _, comment := range decl.Doc.List {
+		if !strings.HasPrefix(comment.Text, opcodeDirectivePrefix) {
+			continue
+		}
+		for _, name := range strings.Fields(strings.TrimPrefix(comment.Text, opcodeDirectivePrefix)) {
+			op, ok := opcodeByName(name)
+			if !ok {
+				log.Fatalf("unknown opcode %s in directive of function %s", name, decl.Name.Name)
+			}
+			opcodes = append(opcodes, op)
+		}
+	}
+	return opcodes
+}
+
+// opcodeByName returns the instruction with the given name.
+func opcodeByName(name string) (vm.Instruction, bool) {
+	for i := 0; i <= 0xFF; i++ {
+		if op := vm.Instruction(i); op.String() == name {
+			return op, true
+		}
+	}
+	return 0, false
+}
+
+// hasDirective returns true if the doc comment of the given function
+// declaration contains the given directive.
+func hasDirective(decl *ast.FuncDecl, directive string) bool {
+	if decl.Doc == nil {
 		return false
 	}
-	_, ok := syscalls[fun.selector.Name][fun.name]
-	return ok
+	for _, comment := range decl.Doc.List {
+		if comment.Text == directive {
+			return true
+		}
+	}
+	return false
+}
+
+func isIntegerType(t types.Type) bool {
+	typ, ok := t.Underlying().(*types.Basic)
+	return ok && typ.Info()&types.IsInteger != 0
 }
 
 func isStringType(t types.Type) bool {
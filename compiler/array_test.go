@@ -0,0 +1,179 @@
+package compiler_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/CityOfZion/neo-storm/vm"
+)
+
+func TestByteArraySizeCheck(t *testing.T) {
+	check := []byte{byte(vm.DUP), byte(vm.SIZE), byte(vm.PUSHBYTES1), 20, byte(vm.NUMEQUAL), byte(vm.THROWIFNOT)}
+
+	src := `package foo
+	func Main(b []byte) []byte {
+		h := [20]byte(b)
+		return h[:]
+	}`
+	if b := compileSource(t, src); !bytes.Contains(b, check) {
+		t.Fatalf("expected the size to be checked, got %x", b)
+	}
+
+	// The length of constants is checked by the compiler.
+	src = `package foo
+	func Main() []byte {
+		h := [20]byte([]byte("01234567890123456789"))
+		return h[:]
+	}`
+	if b := compileSource(t, src); bytes.Contains(b, check) {
+		t.Fatalf("expected no size check, got %x", b)
+	}
+}
+
+func TestByteArrayConstLength(t *testing.T) {
+	src := `package foo
+	func Main() []byte {
+		h := [20]byte([]byte("too short"))
+		return h[:]
+	}`
+	if out := compileError(t, src); !strings.Contains(out, "cannot convert constant of length 9 to [20]byte") {
+		t.Fatalf("expected an error about the length, got:\n%s", out)
+	}
+}
+
+func TestByteArrayElementAssign(t *testing.T) {
+	for _, typ := range []string{"[2]byte", "[]byte"} {
+		src := `package foo
+		func Main() []byte {
+			var d ` + typ + `
+			d[0] = 1
+			return d[:]
+		}`
+		if out := compileError(t, src); !strings.Contains(out, "assigning to an element of a byte slice or array is not supported") {
+			t.Fatalf("%s: expected an error about the assignment, got:\n%s", typ, out)
+		}
+	}
+}
+
+func TestByteArrayZeroValue(t *testing.T) {
+	src := `package foo
+	func Main() []byte {
+		var h [20]byte
+		return h[:]
+	}`
+	// PUSHBYTES20 with 20 zero bytes.
+	zero := append([]byte{20}, make([]byte, 20)...)
+	if b := compileSource(t, src); !bytes.Contains(b, zero) {
+		t.Fatalf("expected 20 zero bytes to be pushed, got %x", b)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	// Items are appended to the array itself, which is left on the stack.
+	src := `package foo
+	func Main(xs []int) []int {
+		xs = append(xs, 1, 2)
+		return xs
+	}`
+	if b := compileSource(t, src); !hasOpcodes(b, vm.DUP, vm.PUSH1, vm.APPEND, vm.DUP, vm.PUSH2, vm.APPEND) {
+		t.Fatalf("expected the items to be appended, got %x", b)
+	}
+
+	// Byte slices are concatenated.
+	src = `package foo
+	func Main(a, b []byte) []byte {
+		return append(a, b...)
+	}`
+	if b := compileSource(t, src); !hasOpcodes(b, vm.CAT) || hasOpcodes(b, vm.APPEND) {
+		t.Fatalf("expected the byte slices to be concatenated, got %x", b)
+	}
+
+	src = `package foo
+	func Main(a, b []int) []int {
+		return append(a, b...)
+	}`
+	if out := compileError(t, src); !strings.Contains(out, "appending a spread slice is only supported for byte slices") {
+		t.Fatalf("expected an error about the spread slice, got:\n%s", out)
+	}
+}
+
+func TestByteArrayConversion(t *testing.T) {
+	src := `package foo
+	func Main(op string, b []byte, x interface{}) []byte {
+		if op == "convert" {
+			h := [20]byte(b)
+			return h[:]
+		}
+		h := x.([20]byte)
+		return h[:]
+	}`
+
+	hash := bytes.Repeat([]byte{1}, 20)
+	for _, op := range []string{"convert", "assert"} {
+		args := []vm.StackItem{vm.NewByteArray([]byte(op)), vm.NewByteArray(hash), vm.NewByteArray(hash)}
+		if res := runContract(t, src, args...).Bytes(); !bytes.Equal(res, hash) {
+			t.Fatalf("%s: expected %x, got %x", op, hash, res)
+		}
+
+		// The VM FAULTs if the length is wrong.
+		short := vm.NewByteArray(hash[:19])
+		v := loadContract(t, nil, src, vm.NewByteArray([]byte(op)), short, short)
+		if err := v.Run(); err == nil {
+			t.Fatalf("%s: expected a FAULT", op)
+		}
+	}
+}
+
+func TestByteArrayOperations(t *testing.T) {
+	src := `package foo
+	func Main(op string, i int, a, b [20]byte) []byte {
+		if op == "zero" {
+			var h [20]byte
+			return h[:]
+		}
+		if op == "slice" {
+			return a[1:3]
+		}
+		if op == "index" {
+			return append([]byte{}, a[i])
+		}
+		if op == "equal" {
+			return append([]byte{}, eq(a == b), eq(a != b))
+		}
+		return nil
+	}
+	func eq(b bool) byte {
+		if b {
+			return 1
+		}
+		return 0
+	}`
+
+	a := make([]byte, 20)
+	for i := range a {
+		a[i] = byte(i + 1)
+	}
+	// The last byte differs, which an integer comparison of the VM would
+	// not see, as the arrays are larger than its integers.
+	other := append(append([]byte{}, a[:19]...), 0xff)
+
+	var cases = []struct {
+		op     string
+		i      int64
+		b      []byte
+		expect []byte
+	}{
+		{"zero", 0, a, make([]byte, 20)},
+		{"slice", 0, a, []byte{2, 3}},
+		{"index", 4, a, []byte{5}},
+		{"equal", 0, a, []byte{1, 0}},
+		{"equal", 0, other, []byte{0, 1}},
+	}
+	for _, c := range cases {
+		args := []vm.StackItem{vm.NewByteArray([]byte(c.op)), vm.NewIntegerInt64(c.i), vm.NewByteArray(a), vm.NewByteArray(c.b)}
+		if res := runContract(t, src, args...).Bytes(); !bytes.Equal(res, c.expect) {
+			t.Fatalf("%s: expected %x, got %x", c.op, c.expect, res)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package compiler_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/CityOfZion/neo-go/pkg/util"
+	"github.com/CityOfZion/neo-storm/compiler"
+	"github.com/CityOfZion/neo-storm/interop/attachment"
+	"github.com/CityOfZion/neo-storm/vm"
+)
+
+// The attachment package is compiled into the contract on top of the
+// engine, transaction and output syscalls.
+func TestAttachmentCompiled(t *testing.T) {
+	src := `package foo
+	import "github.com/CityOfZion/neo-storm/interop/attachment"
+	func Main() []byte {
+		if attachment.ReceivedNEO() > 0 {
+			return attachment.Sender()
+		}
+		return nil
+	}`
+	b := compileSource(t, src)
+	for _, api := range []string{
+		"System.ExecutionEngine.GetScriptContainer",
+		"System.ExecutionEngine.GetExecutingScriptHash",
+		"Neo.Transaction.GetOutputs",
+		"Neo.Transaction.GetReferences",
+		"Neo.Output.GetAssetId",
+	} {
+		if !bytes.Contains(b, syscall(api)) {
+			t.Errorf("expected a syscall to %s", api)
+		}
+	}
+}
+
+func TestAttachment(t *testing.T) {
+	src := `package foo
+	import "github.com/CityOfZion/neo-storm/interop/attachment"
+	func Main(op string) interface{} {
+		if op == "neo" {
+			return attachment.ReceivedNEO()
+		}
+		if op == "gas" {
+			return attachment.ReceivedGAS()
+		}
+		if op == "senders" {
+			return attachment.Senders()
+		}
+		if op == "sender" {
+			return attachment.Sender()
+		}
+		return nil
+	}`
+	script, err := compiler.Compile(strings.NewReader(src), &compiler.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	self, _ := util.Uint160FromScript(script)
+	// The VM pushes the asset IDs in the order of their bytes.
+	var neo, gas util.Uint256
+	copy(neo[:], attachment.NEO)
+	copy(gas[:], attachment.GAS)
+	alice, bob, other := util.Uint160{1}, util.Uint160{2}, util.Uint160{3}
+
+	env := vm.NewEnvironment()
+	prev := &vm.Transaction{
+		Hash: util.Uint256{1},
+		Outputs: []*vm.Output{
+			{AssetID: neo, Value: 10, ScriptHash: alice},
+			{AssetID: gas, Value: 3, ScriptHash: bob},
+		},
+	}
+	env.Chain.AddBlock(1536000000, prev)
+	tx := &vm.Transaction{
+		Hash: util.Uint256{2},
+		Inputs: []*vm.Input{
+			{PrevHash: prev.Hash, PrevIndex: 0},
+			{PrevHash: prev.Hash, PrevIndex: 1},
+		},
+		Outputs: []*vm.Output{
+			{AssetID: neo, Value: 4, ScriptHash: self},
+			{AssetID: gas, Value: 2, ScriptHash: self},
+			{AssetID: neo, Value: 6, ScriptHash: self},
+			// Change and payments to others are not received by the contract.
+			{AssetID: neo, Value: 5, ScriptHash: other},
+			{AssetID: gas, Value: 1, ScriptHash: alice},
+		},
+	}
+	// A transaction that only claims, without spending any outputs.
+	claim := &vm.Transaction{
+		Hash:    util.Uint256{3},
+		Outputs: []*vm.Output{{AssetID: gas, Value: 7, ScriptHash: other}},
+	}
+
+	run := func(t *testing.T, container *vm.Transaction, op string) vm.StackItem {
+		env.Container = container
+		v := vm.New()
+		env.Attach(v)
+		v.Estack().Push(vm.NewByteArray([]byte(op)))
+		v.LoadScript(script)
+		if err := v.Run(); err != nil {
+			t.Fatal(err)
+		}
+		return v.Estack().Pop()
+	}
+
+	t.Run("received", func(t *testing.T) {
+		if res := run(t, tx, "neo").BigInt().Int64(); res != 10 {
+			t.Fatalf("expected 10 NEO, got %d", res)
+		}
+		if res := run(t, tx, "gas").BigInt().Int64(); res != 2 {
+			t.Fatalf("expected 2 GAS, got %d", res)
+		}
+		if res := run(t, claim, "gas").BigInt().Int64(); res != 0 {
+			t.Fatalf("expected no GAS, got %d", res)
+		}
+	})
+	t.Run("senders", func(t *testing.T) {
+		senders := run(t, tx, "senders").Value().([]vm.StackItem)
+		if len(senders) != 2 || !bytes.Equal(senders[0].Bytes(), alice.Bytes()) || !bytes.Equal(senders[1].Bytes(), bob.Bytes()) {
+			t.Fatalf("expected alice and bob, got %v", senders)
+		}
+		if sender := run(t, tx, "sender").Bytes(); !bytes.Equal(sender, alice.Bytes()) {
+			t.Fatalf("expected alice, got %x", sender)
+		}
+	})
+	t.Run("no references", func(t *testing.T) {
+		if senders := run(t, claim, "senders").Value().([]vm.StackItem); len(senders) != 0 {
+			t.Fatalf("expected no senders, got %v", senders)
+		}
+		// The sender is an empty byte array, not false like nil would be.
+		sender := run(t, claim, "sender")
+		if b, ok := sender.(*vm.ByteArray); !ok || len(b.Bytes()) != 0 {
+			t.Fatalf("expected an empty sender, got %T %s", sender, sender)
+		}
+	})
+}
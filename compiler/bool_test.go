@@ -0,0 +1,142 @@
+package compiler_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/CityOfZion/neo-storm/vm"
+)
+
+func TestBoolJumps(t *testing.T) {
+	var cases = []struct {
+		op   string
+		jump vm.Instruction
+	}{
+		{"&&", vm.JMPIFNOT},
+		{"||", vm.JMPIF},
+	}
+	for _, c := range cases {
+		src := `package foo
+		func Main(a, b bool) bool {
+			return a ` + c.op + ` b
+		}`
+
+		// The left side is kept as the result when the jump skips the
+		// right side, which is dropped otherwise. The jump lands right
+		// after the right side is loaded.
+		expect := []byte{
+			byte(vm.DUPFROMALTSTACK), byte(vm.PUSH0), byte(vm.PICKITEM),
+			byte(vm.DUP), byte(c.jump), 7, 0,
+			byte(vm.DROP),
+			byte(vm.DUPFROMALTSTACK), byte(vm.PUSH1), byte(vm.PICKITEM),
+		}
+		if b := compileSource(t, src); !bytes.Contains(b, expect) {
+			t.Fatalf("%s: expected %x in %x", c.op, expect, b)
+		}
+	}
+}
+
+func TestBoolOperators(t *testing.T) {
+	src := `package foo
+	func Main(op string, a, b, c bool) int {
+		if op == "assign" {
+			ok := a && b
+			if ok {
+				return 1
+			}
+			return 0
+		}
+		if op == "return" {
+			if or(a, b) {
+				return 1
+			}
+			return 0
+		}
+		if op == "nested" {
+			if (a && b) || c {
+				return 1
+			}
+			return 0
+		}
+		if op == "for" {
+			i := 0
+			for i < 3 && (a || b) {
+				i++
+			}
+			return i
+		}
+		return -1
+	}
+	func or(a, b bool) bool {
+		return a || b
+	}`
+
+	var cases = []struct {
+		op      string
+		a, b, c bool
+		expect  int64
+	}{
+		{"assign", true, true, false, 1},
+		{"assign", true, false, false, 0},
+		{"assign", false, true, false, 0},
+		{"return", false, false, false, 0},
+		{"return", false, true, false, 1},
+		{"return", true, false, false, 1},
+		{"nested", true, true, false, 1},
+		{"nested", true, false, false, 0},
+		{"nested", false, true, true, 1},
+		{"nested", false, false, false, 0},
+		{"for", false, false, false, 0},
+		{"for", false, true, false, 3},
+		{"for", true, false, false, 3},
+	}
+	for _, c := range cases {
+		args := []vm.StackItem{vm.NewByteArray([]byte(c.op)), vm.NewBoolean(c.a), vm.NewBoolean(c.b), vm.NewBoolean(c.c)}
+		if res := runContract(t, src, args...).BigInt().Int64(); res != c.expect {
+			t.Fatalf("%s %t %t %t: expected %d, got %d", c.op, c.a, c.b, c.c, c.expect, res)
+		}
+	}
+}
+
+// The right operand of && and || is only evaluated when it decides the
+// result, so its side effects do not happen otherwise.
+func TestBoolShortCircuit(t *testing.T) {
+	src := `package foo
+	import "github.com/CityOfZion/neo-storm/interop/runtime"
+	func Main(a bool) int {
+		n := 0
+		if a && notify("and") {
+			n = n + 1
+		}
+		if a || notify("or") {
+			n = n + 10
+		}
+		return n
+	}
+	func notify(name string) bool {
+		runtime.Notify(name)
+		return true
+	}`
+
+	var cases = []struct {
+		a      bool
+		expect int64
+		notify string
+	}{
+		{true, 11, "and"},
+		{false, 10, "or"},
+	}
+	for _, c := range cases {
+		env := vm.NewEnvironment()
+		if res := runContractIn(t, env, src, vm.NewBoolean(c.a)).BigInt().Int64(); res != c.expect {
+			t.Fatalf("%t: expected %d, got %d", c.a, c.expect, res)
+		}
+		if len(env.Notifications) != 1 {
+			t.Fatalf("%t: expected only %q to be notified, got %d notifications", c.a, c.notify, len(env.Notifications))
+		}
+		args := env.Notifications[0].Item.Value().([]vm.StackItem)
+		if name := string(args[0].Bytes()); name != c.notify {
+			t.Fatalf("%t: expected %q to be notified, got %q", c.a, c.notify, name)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package compiler
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"go/ast"
 	"go/constant"
 	"go/token"
@@ -28,12 +29,21 @@ type codegen struct {
 	// Type information
 	typeInfo *types.Info
 
-	// A mapping of func identifiers with their scope.
+	// A mapping of func identifiers with their scope. The identifiers are
+	// qualified with the package path and the receiver type of methods,
+	// see funcName.
 	funcs map[string]*funcScope
 
 	// Current funcScope being converted.
 	scope *funcScope
 
+	// A mapping of event names to the functions that declare them.
+	events map[string]*funcScope
+
+	// Variables of the current function that hold the trigger, which is
+	// constant when compiling for a single trigger.
+	triggerVars map[types.Object]bool
+
 	// Label table for recording jump destinations.
 	l []int
 }
@@ -138,10 +148,10 @@ func (c *codegen) convertFuncDecl(file *ast.File, decl *ast.FuncDecl) {
 		ok bool
 	)
 
-	f, ok = c.funcs[decl.Name.Name]
+	f, ok = c.funcs[c.funcNameOf(decl)]
 	if ok {
-		// If this function is a syscall we will not convert it to bytecode.
-		if isSyscall(f) {
+		// If this function is a syscall or opcodes we will not convert it to bytecode.
+		if isInlined(f) {
 			return
 		}
 		c.setLabel(f.label)
@@ -150,7 +160,8 @@ func (c *codegen) convertFuncDecl(file *ast.File, decl *ast.FuncDecl) {
 	}
 
 	c.scope = f
-	ast.Inspect(decl, c.scope.analyzeVoidCalls) // @OPTIMIZE
+	start := c.prog.Len()
+	c.triggerVars = c.analyzeTriggerVars(decl)
 
 	// All globals copied into the scope of the function need to be added
 	// to the stack size of the function.
@@ -179,9 +190,10 @@ func (c *codegen) convertFuncDecl(file *ast.File, decl *ast.FuncDecl) {
 
 	// Load the arguments in scope.
 	for _, arg := range decl.Type.Params.List {
-		name := arg.Names[0].Name // for now.
-		l := c.scope.newLocal(name)
-		c.emitStoreLocal(l)
+		for _, name := range arg.Names {
+			l := c.scope.newLocal(name.Name)
+			c.emitStoreLocal(l)
+		}
 	}
 	// Load in all the global variables in to the scope of the function.
 	// This is not necessary for syscalls.
@@ -197,9 +209,16 @@ func (c *codegen) convertFuncDecl(file *ast.File, decl *ast.FuncDecl) {
 		emitOpcode(c.prog, vm.DROP)
 		emitOpcode(c.prog, vm.RET)
 	}
+	c.addFunction(f, start)
 }
 
 func (c *codegen) Visit(node ast.Node) ast.Visitor {
+	if _, ok := node.(ast.Stmt); ok {
+		if _, ok := node.(*ast.BlockStmt); !ok {
+			c.addSequencePoint(node)
+		}
+	}
+
 	switch n := node.(type) {
 
 	// General declarations.
@@ -215,11 +234,27 @@ func (c *codegen) Visit(node ast.Node) ast.Visitor {
 					l := c.scope.newLocal(t.Names[i].Name)
 					c.emitStoreLocal(l)
 				}
+				// Variables declared without a value are initialized
+				// to the zero value of their type.
+				// var hash [20]byte
+				if n.Tok == token.VAR && len(t.Values) == 0 {
+					for _, name := range t.Names {
+						c.emitDefault(c.typeInfo.ObjectOf(name).Type())
+						l := c.scope.newLocal(name.Name)
+						c.emitStoreLocal(l)
+					}
+				}
 			}
 		}
 		return nil
 
 	case *ast.AssignStmt:
+		// Comma-ok type assertions.
+		// v, ok := args[0].([20]byte)
+		if assert, ok := n.Rhs[0].(*ast.TypeAssertExpr); ok && len(n.Lhs) == 2 {
+			c.convertCommaOkAssert(n, assert)
+			return nil
+		}
 		for i := 0; i < len(n.Lhs); i++ {
 			switch t := n.Lhs[i].(type) {
 			case *ast.Ident:
@@ -237,22 +272,26 @@ func (c *codegen) Visit(node ast.Node) ast.Visitor {
 				}
 
 			case *ast.SelectorExpr:
-				switch expr := t.X.(type) {
-				case *ast.Ident:
-					ast.Walk(c, n.Rhs[i])
-					typ := c.typeInfo.ObjectOf(expr).Type().Underlying()
-					if strct, ok := typ.(*types.Struct); ok {
-						c.emitLoadLocal(expr.Name)            // load the struct
-						i := indexOfStruct(strct, t.Sel.Name) // get the index of the field
-						c.emitStoreStructField(i)             // store the field
-					}
-				default:
-					log.Fatal("nested selector assigns not supported yet")
+				sel := c.typeInfo.Selections[t]
+				if sel == nil || sel.Kind() != types.FieldVal {
+					log.Fatalf("cannot assign to %s", t.Sel.Name)
 				}
+				ast.Walk(c, n.Rhs[i])
+				ast.Walk(c, t.X) // load the struct
+				// Promoted fields are stored inside their embedded struct.
+				index := sel.Index()
+				for _, i := range index[:len(index)-1] {
+					c.emitLoadField(i)
+				}
+				c.emitStoreStructField(index[len(index)-1]) // store the field
 
 			// Assignments to index expressions.
 			// slice[0] = 10
 			case *ast.IndexExpr:
+				// Byte arrays are not arrays of items in the VM.
+				if isByteType(c.typeInfo.TypeOf(t.X)) {
+					log.Fatalf("%s: assigning to an element of a byte slice or array is not supported", c.position(t))
+				}
 				ast.Walk(c, n.Rhs[i])
 				name := t.X.(*ast.Ident).Name
 				c.emitLoadLocal(name)
@@ -290,6 +329,22 @@ func (c *codegen) Visit(node ast.Node) ast.Visitor {
 		lElse := c.newLabel()
 		lElseEnd := c.newLabel()
 
+		if n.Init != nil {
+			ast.Walk(c, n.Init)
+		}
+
+		// Only the branch that is taken is converted if the condition is
+		// known at compile time.
+		// if trigger == runtime.Verification() {}
+		if val, ok := c.constCond(n.Cond); ok {
+			if val {
+				ast.Walk(c, n.Body)
+			} else if n.Else != nil {
+				ast.Walk(c, n.Else)
+			}
+			return nil
+		}
+
 		if n.Cond != nil {
 			ast.Walk(c, n.Cond)
 			emitJmp(c.prog, vm.JMPIFNOT, int16(lElse))
@@ -321,47 +376,33 @@ func (c *codegen) Visit(node ast.Node) ast.Visitor {
 		return nil
 
 	case *ast.CompositeLit:
-		var typ types.Type
-
-		switch t := n.Type.(type) {
-		case *ast.Ident:
-			typ = c.typeInfo.ObjectOf(t).Type().Underlying()
-		case *ast.SelectorExpr:
-			typ = c.typeInfo.ObjectOf(t.Sel).Type().Underlying()
+		switch typ := c.typeInfo.TypeOf(n).Underlying().(type) {
+		case *types.Struct:
+			c.convertStruct(n)
 		default:
-			ln := len(n.Elts)
 			// ByteArrays need a different approach then normal arrays.
-			if isByteArray(n, c.typeInfo) {
+			if isByteType(typ) {
 				c.convertByteArray(n)
 				return nil
 			}
+			ln := len(n.Elts)
 			for i := ln - 1; i >= 0; i-- {
-				c.emitLoadConst(c.typeInfo.Types[n.Elts[i]])
+				t := c.typeInfo.Types[n.Elts[i]]
+				if t.Value != nil {
+					c.emitLoadConst(t)
+				} else {
+					ast.Walk(c, n.Elts[i])
+				}
 			}
 			emitInt(c.prog, int64(ln))
 			emitOpcode(c.prog, vm.PACK)
-			return nil
-		}
-
-		switch typ.(type) {
-		case *types.Struct:
-			c.convertStruct(n)
 		}
-
 		return nil
 
 	case *ast.BinaryExpr:
 		switch n.Op {
-		case token.LAND:
-			ast.Walk(c, n.X)
-			emitJmp(c.prog, vm.JMPIFNOT, int16(len(c.l)-1))
-			ast.Walk(c, n.Y)
-			return nil
-
-		case token.LOR:
-			ast.Walk(c, n.X)
-			emitJmp(c.prog, vm.JMPIF, int16(len(c.l)-2))
-			ast.Walk(c, n.Y)
+		case token.LAND, token.LOR:
+			c.convertBoolExpr(n)
 			return nil
 
 		default:
@@ -381,6 +422,24 @@ func (c *codegen) Visit(node ast.Node) ast.Visitor {
 			ast.Walk(c, n.X)
 			ast.Walk(c, n.Y)
 
+			// Fixed size byte arrays can be larger then the VM integers,
+			// hence they are compared as byte arrays.
+			if n.Op == token.NEQ {
+				if _, ok := byteArrayLen(c.typeInfo.TypeOf(n.X)); ok {
+					emitOpcode(c.prog, vm.EQUAL)
+					emitOpcode(c.prog, vm.NOT)
+					return nil
+				}
+			}
+
+			// Integers are compared by their value, the VM can represent
+			// them as integers and byte arrays.
+			// runtime.GetTrigger() == runtime.Verification()
+			if n.Op == token.EQL && isIntegerType(c.typeInfo.TypeOf(n.X)) {
+				emitOpcode(c.prog, vm.NUMEQUAL)
+				return nil
+			}
+
 			// VM has separate opcode for string concatenation
 			if n.Op == token.ADD {
 				typ, ok := tinfo.Type.Underlying().(*types.Basic)
@@ -396,6 +455,17 @@ func (c *codegen) Visit(node ast.Node) ast.Visitor {
 		}
 
 	case *ast.CallExpr:
+		// Calls that are resolved at compile time, like len on fixed
+		// size arrays, are loaded as a constant.
+		if t := c.typeInfo.Types[n]; t.Value != nil {
+			c.emitLoadConst(t)
+			return nil
+		}
+		if c.typeInfo.Types[n.Fun].IsType() {
+			c.convertConversion(n)
+			return nil
+		}
+
 		var (
 			f         *funcScope
 			ok        bool
@@ -405,7 +475,7 @@ func (c *codegen) Visit(node ast.Node) ast.Visitor {
 
 		switch fun := n.Fun.(type) {
 		case *ast.Ident:
-			f, ok = c.funcs[fun.Name]
+			f, ok = c.funcs[c.funcNameOfIdent(fun)]
 			if !ok && !isBuiltin {
 				log.Fatalf("could not resolve function %s", fun.Name)
 			}
@@ -413,24 +483,46 @@ func (c *codegen) Visit(node ast.Node) ast.Visitor {
 			// If this is a method call we need to walk the AST to load the struct locally.
 			// Otherwise this is a function call from a imported package and we can call it
 			// directly.
-			if c.typeInfo.Selections[fun] != nil {
+			if sel := c.typeInfo.Selections[fun]; sel != nil {
 				ast.Walk(c, fun.X)
+				// Promoted methods are called on the embedded struct.
+				index := sel.Index()
+				for _, i := range index[:len(index)-1] {
+					c.emitLoadField(i)
+				}
 				// Dont forget to add 1 extra argument when its a method.
 				numArgs++
 			}
 
-			f, ok = c.funcs[fun.Sel.Name]
-			// @FIXME this could cause runtime errors.
-			f.selector = fun.X.(*ast.Ident)
-			if !ok {
+			f, ok = c.funcs[c.funcNameOfIdent(fun.Sel)]
+			if !ok && !isBuiltin {
 				log.Fatalf("could not resolve function %s", fun.Sel.Name)
 			}
-		case *ast.ArrayType:
-			// For now we will assume that there is only 1 argument passed which
-			// will be a basic literal (string kind). This only to handle string
-			// to byte slice conversions. E.G. []byte("foobar")
-			arg := n.Args[0].(*ast.BasicLit)
-			c.emitLoadConst(c.typeInfo.Types[arg])
+		}
+
+		if ok && f.value.Value != nil {
+			c.emitLoadConst(f.value)
+			return nil
+		}
+		if c.isTriggerCall(n) {
+			c.emitLoadConst(types.TypeAndValue{Type: c.typeInfo.TypeOf(n), Value: c.buildInfo.trigger})
+			return nil
+		}
+		if ok && f.appcall {
+			c.convertAppCall(n)
+			return nil
+		}
+		if ok && f.event != "" {
+			c.convertEvent(n, f)
+			return nil
+		}
+		if ok && f.builtin {
+			c.convertInteropBuiltin(n, f)
+			return nil
+		}
+
+		if isBuiltin && builtinName(n.Fun) == "append" {
+			c.convertAppend(n)
 			return nil
 		}
 
@@ -440,38 +532,8 @@ func (c *codegen) Visit(node ast.Node) ast.Visitor {
 		}
 		// Do not swap for builtin functions.
 		if !isBuiltin {
-			if numArgs == 2 {
-				emitOpcode(c.prog, vm.SWAP)
-			} else if numArgs == 3 {
-				emitInt(c.prog, 2)
-				emitOpcode(c.prog, vm.XSWAP)
-			} else {
-				half := int(numArgs / 2)
-
-				for i := 0; i < half; i++ {
-					to := numArgs - 1 - i
-
-					emitInt(c.prog, int64(to))
-					emitOpcode(c.prog, vm.PICK)
-
-					emitInt(c.prog, int64(i+1))
-					emitOpcode(c.prog, vm.PICK)
-
-					emitInt(c.prog, int64(to+2))
-					emitOpcode(c.prog, vm.XSWAP)
-					emitOpcode(c.prog, vm.DROP)
-
-					emitInt(c.prog, int64(i+1))
-					emitOpcode(c.prog, vm.XSWAP)
-					emitOpcode(c.prog, vm.DROP)
-				}
-			}
-			if numArgs > 3 {
-				for i := 1; i < numArgs; i++ {
-					emitInt(c.prog, int64(i))
-					emitOpcode(c.prog, vm.ROLL)
-				}
-			}
+			numArgs -= c.packVariadicArgs(n)
+			c.emitReverse(numArgs)
 		}
 
 		// Check builtin first to avoid nil pointer on funcScope!
@@ -479,8 +541,13 @@ func (c *codegen) Visit(node ast.Node) ast.Visitor {
 			// Use the ident to check, builtins are not in func scopes.
 			// We can be sure builtins are of type *ast.Ident.
 			c.convertBuiltin(n)
-		} else if isSyscall(f) {
-			c.convertSyscall(f.selector.Name, f.name)
+		} else if isInlined(f) {
+			if isSyscall(f) {
+				c.convertSyscall(f.syscall)
+			}
+			for _, op := range f.opcodes {
+				emitOpcode(c.prog, op)
+			}
 		} else {
 			emitCall(c.prog, vm.CALL, int16(f.label))
 		}
@@ -488,22 +555,57 @@ func (c *codegen) Visit(node ast.Node) ast.Visitor {
 		return nil
 
 	case *ast.SelectorExpr:
-		switch t := n.X.(type) {
-		case *ast.Ident:
-			typ := c.typeInfo.ObjectOf(t).Type().Underlying()
-			if strct, ok := typ.(*types.Struct); ok {
-				c.emitLoadLocal(t.Name) // load the struct
-				i := indexOfStruct(strct, n.Sel.Name)
-				c.emitLoadField(i) // load the field
-			}
-		default:
-			log.Fatal("nested selectors not supported yet")
+		// Constants from imported packages are resolved by the type checker.
+		if t := c.typeInfo.Types[n]; t.Value != nil {
+			c.emitLoadConst(t)
+			return nil
+		}
+		sel := c.typeInfo.Selections[n]
+		if sel == nil || sel.Kind() != types.FieldVal {
+			log.Fatalf("could not resolve selector %s", n.Sel.Name)
+		}
+		ast.Walk(c, n.X) // load the struct
+		// Embedded structs are stored as a struct inside the field that
+		// embeds them, promoted fields are loaded by following the
+		// index path through these fields.
+		for _, i := range sel.Index() {
+			c.emitLoadField(i) // load the field
 		}
 		return nil
 
 	case *ast.UnaryExpr:
+		// Negative constants are pushed as they are.
+		// x := -129
+		if tv := c.typeInfo.Types[n]; tv.Value != nil {
+			c.emitLoadConst(tv)
+			return nil
+		}
 		ast.Walk(c, n.X)
-		c.convertToken(n.Op)
+		switch n.Op {
+		case token.AND:
+			// Structs are already passed by reference in the VM, taking
+			// their address is a no-op.
+			// storage.PutStruct(ctx, key, &order)
+		case token.SUB:
+			emitOpcode(c.prog, vm.NEGATE)
+		default:
+			c.convertToken(n.Op)
+		}
+		return nil
+
+	// The results of calls that are used as a statement are dropped.
+	// storage.GetStruct(ctx, key, &order)
+	case *ast.ExprStmt:
+		ast.Walk(c, n.X)
+		switch t := c.typeInfo.TypeOf(n.X).(type) {
+		case nil:
+		case *types.Tuple:
+			for i := 0; i < t.Len(); i++ {
+				emitOpcode(c.prog, vm.DROP)
+			}
+		default:
+			emitOpcode(c.prog, vm.DROP)
+		}
 		return nil
 
 	case *ast.IncDecStmt:
@@ -524,6 +626,15 @@ func (c *codegen) Visit(node ast.Node) ast.Visitor {
 		// This will load local whatever X is.
 		ast.Walk(c, n.X)
 
+		// The VM can not pick items from a byte array, hence we take
+		// a substring of 1 byte.
+		if isByteType(c.typeInfo.TypeOf(n.X).Underlying()) {
+			ast.Walk(c, n.Index)
+			emitInt(c.prog, 1)
+			emitOpcode(c.prog, vm.SUBSTR)
+			return nil
+		}
+
 		switch n.Index.(type) {
 		case *ast.BasicLit:
 			t := c.typeInfo.Types[n.Index]
@@ -541,19 +652,26 @@ func (c *codegen) Visit(node ast.Node) ast.Visitor {
 			fend   = c.newLabel()
 		)
 
-		// Walk the initializer and condition.
-		ast.Walk(c, n.Init)
+		// Walk the initializer and condition, both can be omitted
+		// like in: for iterator.Next(it) {}
+		if n.Init != nil {
+			ast.Walk(c, n.Init)
+		}
 
 		// Set label and walk the condition.
 		c.setLabel(fstart)
-		ast.Walk(c, n.Cond)
+		if n.Cond != nil {
+			ast.Walk(c, n.Cond)
 
-		// Jump if the condition is false
-		emitJmp(c.prog, vm.JMPIFNOT, int16(fend))
+			// Jump if the condition is false
+			emitJmp(c.prog, vm.JMPIFNOT, int16(fend))
+		}
 
 		// Walk body followed by the iterator (post stmt).
 		ast.Walk(c, n.Body)
-		ast.Walk(c, n.Post)
+		if n.Post != nil {
+			ast.Walk(c, n.Post)
+		}
 
 		// Jump back to condition.
 		emitJmp(c.prog, vm.JMP, int16(fstart))
@@ -564,19 +682,165 @@ func (c *codegen) Visit(node ast.Node) ast.Visitor {
 	// We dont really care about assertions for the core logic.
 	// The only thing we need is to please the compiler type checking.
 	// For this to work properly, we only need to walk the expression
-	// not the assertion type.
+	// not the assertion type. The exception are fixed size byte arrays
+	// which get their length checked at runtime.
+	// to := args[0].([20]byte)
 	case *ast.TypeAssertExpr:
 		ast.Walk(c, n.X)
+		if size, ok := byteArrayLen(c.typeInfo.TypeOf(n.Type)); ok {
+			c.emitCheckSize(size)
+		}
+		return nil
+
+	case *ast.TypeSwitchStmt:
+		c.convertTypeSwitch(n)
+		return nil
+
+	// Slicing is only supported on byte slices and arrays.
+	// hash[:] or data[2:4]
+	case *ast.SliceExpr:
+		if !isByteType(c.typeInfo.TypeOf(n.X).Underlying()) {
+			log.Fatal("slicing is only supported on byte slices and arrays")
+		}
+		ast.Walk(c, n.X)
+		if n.Low == nil && n.High == nil {
+			return nil
+		}
+		if n.Low != nil {
+			ast.Walk(c, n.Low)
+		} else {
+			emitInt(c.prog, 0)
+		}
+		if n.High != nil {
+			ast.Walk(c, n.High)
+		} else {
+			emitOpcode(c.prog, vm.OVER)
+			emitOpcode(c.prog, vm.SIZE)
+		}
+		// SUBSTR takes the count instead of the upper bound.
+		emitOpcode(c.prog, vm.OVER)
+		emitOpcode(c.prog, vm.SUB)
+		emitOpcode(c.prog, vm.SUBSTR)
 		return nil
 	}
 	return c
 }
 
-func (c *codegen) convertSyscall(api, name string) {
-	api, ok := syscalls[api][name]
-	if !ok {
-		log.Fatalf("unknown VM syscall api: %s", name)
+// convertBoolExpr converts the logical && and || operators. The result of
+// the expression is left on the stack so it can be used in any context,
+// like an assignment, a return or the condition of an if or for statement.
+// The right side is only evaluated when the left side does not decide the
+// result already.
+func (c *codegen) convertBoolExpr(expr *ast.BinaryExpr) {
+	end := c.newLabel()
+
+	ast.Walk(c, expr.X)
+	// Keep a copy of the left side, which is the result of the
+	// expression if we short circuit.
+	emitOpcode(c.prog, vm.DUP)
+	if expr.Op == token.LAND {
+		emitJmp(c.prog, vm.JMPIFNOT, int16(end))
+	} else {
+		emitJmp(c.prog, vm.JMPIF, int16(end))
+	}
+	emitOpcode(c.prog, vm.DROP)
+	ast.Walk(c, expr.Y)
+
+	c.setLabel(end)
+}
+
+// emitReverse reverses the order of the top num items on the stack.
+func (c *codegen) emitReverse(num int) {
+	switch num {
+	case 0, 1:
+	case 2:
+		emitOpcode(c.prog, vm.SWAP)
+	case 3:
+		emitInt(c.prog, 2)
+		emitOpcode(c.prog, vm.XSWAP)
+	default:
+		for i := 1; i < num; i++ {
+			emitInt(c.prog, int64(i))
+			emitOpcode(c.prog, vm.ROLL)
+		}
+	}
+}
+
+// packVariadicArgs packs the variadic arguments of the call, which are on
+// top of the stack, into an array, the same way the VM expects them for
+// runtime.Notify. If the arguments are spread from a slice f(xs...) the
+// slice is passed as is. It returns the number of arguments that were
+// removed from the stack by packing them.
+func (c *codegen) packVariadicArgs(expr *ast.CallExpr) int {
+	sig, ok := c.typeInfo.TypeOf(expr.Fun).(*types.Signature)
+	if !ok || !sig.Variadic() || expr.Ellipsis.IsValid() {
+		return 0
+	}
+	varSize := len(expr.Args) - sig.Params().Len() + 1
+	c.emitReverse(varSize)
+	emitInt(c.prog, int64(varSize))
+	emitOpcode(c.prog, vm.PACK)
+	return varSize - 1
+}
+
+// convertAppCall converts a call to another contract. The called contract
+// expects the method on top of the stack followed by the arguments. If the
+// script hash is known at compile time it is embedded in the APPCALL,
+// otherwise it is pushed on top of the stack and called with dynamic invoke.
+// contract.Call(hash, "transfer", from, to, amount)
+func (c *codegen) convertAppCall(expr *ast.CallExpr) {
+	hash, static := c.constByteArray(expr.Args[0])
+	if !static {
+		hash = make([]byte, 20)
+		c.buildInfo.dynamicInvoke = true
+	}
+
+	args := expr.Args
+	if static {
+		args = args[1:]
+	}
+	for _, arg := range args {
+		ast.Walk(c, arg)
+	}
+	numArgs := len(args) - c.packVariadicArgs(expr)
+	c.emitReverse(numArgs)
+
+	emitAppCall(c.prog, hash)
+}
+
+// convertEvent converts a call to a function declared as event into a
+// notification of an array with the event name followed by the arguments.
+// Transfer(from, to, amount) => runtime.Notify("transfer", from, to, amount)
+func (c *codegen) convertEvent(expr *ast.CallExpr, f *funcScope) {
+	emitString(c.prog, f.event)
+	for _, arg := range expr.Args {
+		ast.Walk(c, arg)
+	}
+	numArgs := len(expr.Args) + 1
+	c.emitReverse(numArgs)
+	emitInt(c.prog, int64(numArgs))
+	emitOpcode(c.prog, vm.PACK)
+	c.convertSyscall("Neo.Runtime.Notify")
+}
+
+// registerEvent adds the event declared by the given function to the events
+// of the program, which are exported in the ABI.
+func (c *codegen) registerEvent(f *funcScope) {
+	if other, ok := c.events[f.event]; ok {
+		log.Fatalf("%s: event %s is already declared by %s", c.position(f.decl), f.event, other.name)
+	}
+	sig := c.typeInfo.Defs[f.decl.Name].Type().(*types.Signature)
+	if len(f.decl.Body.List) > 0 || sig.Results().Len() > 0 {
+		log.Fatalf("%s: event %s must have an empty body and no results", c.position(f.decl), f.name)
 	}
+	c.events[f.event] = f
+	c.buildInfo.events = append(c.buildInfo.events, Event{
+		Name:       f.event,
+		Parameters: abiParameters(sig.Params()),
+	})
+}
+
+func (c *codegen) convertSyscall(api string) {
 	emitSyscall(c.prog, api)
 
 	// This NOP instruction is basically not needed, but if we do, we have a
@@ -585,15 +849,7 @@ func (c *codegen) convertSyscall(api, name string) {
 }
 
 func (c *codegen) convertBuiltin(expr *ast.CallExpr) {
-	var name string
-	switch t := expr.Fun.(type) {
-	case *ast.Ident:
-		name = t.Name
-	case *ast.SelectorExpr:
-		name = t.Sel.Name
-	}
-
-	switch name {
+	switch builtinName(expr.Fun) {
 	case "len":
 		arg := expr.Args[0]
 		typ := c.typeInfo.Types[arg].Type
@@ -602,8 +858,6 @@ func (c *codegen) convertBuiltin(expr *ast.CallExpr) {
 		} else {
 			emitOpcode(c.prog, vm.ARRAYSIZE)
 		}
-	case "append":
-		emitOpcode(c.prog, vm.APPEND)
 	case "SHA256":
 		emitOpcode(c.prog, vm.SHA256)
 	case "SHA1":
@@ -616,32 +870,402 @@ func (c *codegen) convertBuiltin(expr *ast.CallExpr) {
 		emitOpcode(c.prog, vm.EQUAL)
 	case "FromAddress":
 		// We can be sure that this is a ast.BasicLit just containing a simple
-		// address string. Note that the string returned from calling Value will
-		// contain double qoutes that need to be stripped.
-		addressStr := expr.Args[0].(*ast.BasicLit).Value
-		addressStr = strings.Replace(addressStr, "\"", "", 2)
-		uint160, err := crypto.Uint160DecodeAddress(addressStr)
-		if err != nil {
-			log.Fatal(err)
+		// address string.
+		emitBytes(c.prog, addressHash(expr.Args[0].(*ast.BasicLit)))
+	}
+}
+
+// convertAppend converts a call to append. Arrays are references in the VM,
+// the items are appended to the array itself which is then left on the
+// stack. Byte slices are values and get concatenated.
+// xs = append(xs, x, y)
+func (c *codegen) convertAppend(expr *ast.CallExpr) {
+	ast.Walk(c, expr.Args[0])
+	isBytes := isByteType(c.typeInfo.TypeOf(expr.Args[0]))
+	if expr.Ellipsis.IsValid() && !isBytes {
+		log.Fatalf("%s: appending a spread slice is only supported for byte slices", c.position(expr))
+	}
+	for _, arg := range expr.Args[1:] {
+		if isBytes {
+			ast.Walk(c, arg)
+			emitOpcode(c.prog, vm.CAT)
+			continue
 		}
-		bytes := uint160.Bytes()
-		emitBytes(c.prog, bytes)
+		emitOpcode(c.prog, vm.DUP)
+		ast.Walk(c, arg)
+		emitOpcode(c.prog, vm.APPEND)
+	}
+}
+
+// convertInteropBuiltin converts a call to one of the interopBuiltins.
+func (c *codegen) convertInteropBuiltin(expr *ast.CallExpr, f *funcScope) {
+	switch f.name {
+	case "PutStruct":
+		c.convertPutStruct(expr)
+	case "GetStruct":
+		c.convertGetStruct(expr)
+	default:
+		log.Fatalf("%s: no builtin implementation of %s", c.position(expr), f.name)
+	}
+}
+
+// convertPutStruct serializes the struct and puts it at the given key.
+// storage.PutStruct(ctx, key, order)
+func (c *codegen) convertPutStruct(expr *ast.CallExpr) {
+	for _, arg := range expr.Args {
+		ast.Walk(c, arg)
+	}
+	// Serialize the struct and reverse ctx, key and value into the order
+	// the syscall expects them.
+	c.convertSyscall("Neo.Runtime.Serialize")
+	emitInt(c.prog, 2)
+	emitOpcode(c.prog, vm.XSWAP)
+	c.convertSyscall("Neo.Storage.Put")
+}
+
+// convertGetStruct loads the struct stored at the given key into the struct
+// the third argument points to. Structs are references in the VM, so the
+// fields of the stored struct are copied into it. It leaves false on the
+// stack if the key does not exist, in which case the struct is not changed.
+// ok := storage.GetStruct(ctx, key, &order)
+func (c *codegen) convertGetStruct(expr *ast.CallExpr) {
+	typ := c.typeInfo.TypeOf(expr.Args[2])
+	ptr, ok := typ.(*types.Pointer)
+	if !ok {
+		log.Fatalf("%s: GetStruct needs a pointer to a struct, like &s, got %s", c.position(expr), typ)
+	}
+	strct, ok := ptr.Elem().Underlying().(*types.Struct)
+	if !ok {
+		log.Fatalf("%s: GetStruct needs a pointer to a struct, like &s, got %s", c.position(expr), typ)
+	}
+
+	lMissing := c.newLabel()
+	lEnd := c.newLabel()
+
+	for _, arg := range expr.Args {
+		ast.Walk(c, arg)
+	}
+	// Move the struct below the context and the key.
+	emitOpcode(c.prog, vm.ROT)
+	emitOpcode(c.prog, vm.ROT)
+	emitOpcode(c.prog, vm.SWAP)
+	c.convertSyscall("Neo.Storage.Get")
+
+	// Missing keys have an empty value.
+	emitOpcode(c.prog, vm.DUP)
+	emitOpcode(c.prog, vm.SIZE)
+	emitJmp(c.prog, vm.JMPIFNOT, int16(lMissing))
+
+	c.convertSyscall("Neo.Runtime.Deserialize")
+	for i := 0; i < strct.NumFields(); i++ {
+		emitOpcode(c.prog, vm.OVER)
+		emitInt(c.prog, int64(i))
+		emitInt(c.prog, 2)
+		emitOpcode(c.prog, vm.PICK)
+		c.emitLoadField(i)
+		emitOpcode(c.prog, vm.SETITEM)
+	}
+	emitOpcode(c.prog, vm.DROP)
+	emitOpcode(c.prog, vm.DROP)
+	emitOpcode(c.prog, vm.PUSHT)
+	emitJmp(c.prog, vm.JMP, int16(lEnd))
+
+	c.setLabel(lMissing)
+	emitOpcode(c.prog, vm.DROP)
+	emitOpcode(c.prog, vm.DROP)
+	emitOpcode(c.prog, vm.PUSHF)
+	c.setLabel(lEnd)
+}
+
+// addressHash returns the script hash of the address in the given string
+// literal. Note that the string returned from calling Value will contain
+// double qoutes that need to be stripped.
+func addressHash(lit *ast.BasicLit) []byte {
+	addressStr := strings.Replace(lit.Value, "\"", "", 2)
+	uint160, err := crypto.Uint160DecodeAddress(addressStr)
+	if err != nil {
+		log.Fatal(err)
 	}
+	return uint160.Bytes()
 }
 
 func (c *codegen) convertByteArray(lit *ast.CompositeLit) {
-	buf := make([]byte, len(lit.Elts))
-	for i := 0; i < len(lit.Elts); i++ {
-		t := c.typeInfo.Types[lit.Elts[i]]
-		val, _ := constant.Int64Val(t.Value)
-		buf[i] = byte(val)
+	buf, ok := c.byteArrayLit(lit)
+	if !ok {
+		log.Fatal("byte array literals can only contain constant values")
 	}
 	emitBytes(c.prog, buf)
 }
 
+// byteArrayLit returns the value of a byte array literal. It returns false
+// if not all of its elements are constant.
+func (c *codegen) byteArrayLit(lit *ast.CompositeLit) ([]byte, bool) {
+	size := len(lit.Elts)
+	if n, ok := byteArrayLen(c.typeInfo.TypeOf(lit)); ok {
+		size = n
+	}
+	buf := make([]byte, size)
+	index := 0
+	for _, elt := range lit.Elts {
+		// Indexed elements [20]byte{19: 0x01}
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			key, _ := constant.Int64Val(c.typeInfo.Types[kv.Key].Value)
+			index = int(key)
+			elt = kv.Value
+		}
+		t := c.typeInfo.Types[elt]
+		if t.Value == nil {
+			return nil, false
+		}
+		val, _ := constant.Int64Val(t.Value)
+		buf[index] = byte(val)
+		index++
+	}
+	return buf, true
+}
+
+// constByteArray returns the value of a byte array expression that is known
+// at compile time. These are literals with constant elements and conversions
+// of constant strings or addresses.
+// [20]byte{0x01, ...}, [20]byte([]byte("...")) or [20]byte(util.FromAddress("A..."))
+func (c *codegen) constByteArray(expr ast.Expr) ([]byte, bool) {
+	switch n := expr.(type) {
+	case *ast.ParenExpr:
+		return c.constByteArray(n.X)
+	case *ast.CompositeLit:
+		if !isByteType(c.typeInfo.TypeOf(n)) {
+			return nil, false
+		}
+		return c.byteArrayLit(n)
+	case *ast.CallExpr:
+		if isBuiltin(n.Fun) && builtinName(n.Fun) == "FromAddress" {
+			return addressHash(n.Args[0].(*ast.BasicLit)), true
+		}
+		if !c.typeInfo.Types[n.Fun].IsType() {
+			return nil, false
+		}
+		if t := c.typeInfo.Types[n.Args[0]]; t.Value != nil && t.Value.Kind() == constant.String {
+			return []byte(constant.StringVal(t.Value)), true
+		}
+		return c.constByteArray(n.Args[0])
+	}
+	return nil, false
+}
+
+// convertConversion converts type conversions. Most of the conversions
+// are a no-op in the VM, except conversions to fixed size byte arrays
+// which need their length checked.
+// [20]byte(b) or string(b)
+func (c *codegen) convertConversion(expr *ast.CallExpr) {
+	arg := expr.Args[0]
+	to := c.typeInfo.TypeOf(expr.Fun)
+	from := c.typeInfo.TypeOf(arg)
+
+	t := c.typeInfo.Types[arg]
+	if t.Value != nil {
+		c.emitLoadConst(t)
+	} else {
+		ast.Walk(c, arg)
+	}
+
+	size, ok := byteArrayLen(to)
+	if !ok || types.Identical(from.Underlying(), to.Underlying()) {
+		return
+	}
+	// The length of converted constants is known at compile time.
+	// [20]byte([]byte("..."))
+	if inner, ok := arg.(*ast.CallExpr); ok && c.typeInfo.Types[inner.Fun].IsType() {
+		if v := c.typeInfo.Types[inner.Args[0]].Value; v != nil && v.Kind() == constant.String {
+			if l := len(constant.StringVal(v)); l != size {
+				log.Fatalf("cannot convert constant of length %d to %s", l, to)
+			}
+			return
+		}
+	}
+	c.emitCheckSize(size)
+}
+
+// convertCommaOkAssert converts a type assertion that also returns whether
+// the assertion succeeded. If it did not, the value is the zero value of
+// the asserted type.
+// v, ok := args[0].([20]byte)
+func (c *codegen) convertCommaOkAssert(stmt *ast.AssignStmt, expr *ast.TypeAssertExpr) {
+	typ := c.typeInfo.TypeOf(expr.Type)
+	lOk := c.newLabel()
+
+	ast.Walk(c, expr.X)
+	emitOpcode(c.prog, vm.DUP)
+	c.emitTypeCheck(expr.Type, typ)
+	emitOpcode(c.prog, vm.DUP)
+	emitJmp(c.prog, vm.JMPIF, int16(lOk))
+	// Replace the value with the zero value of the type.
+	emitOpcode(c.prog, vm.NIP)
+	c.emitDefault(typ)
+	emitOpcode(c.prog, vm.SWAP)
+	c.setLabel(lOk)
+
+	for i := len(stmt.Lhs) - 1; i >= 0; i-- {
+		ident, ok := stmt.Lhs[i].(*ast.Ident)
+		if !ok {
+			log.Fatalf("%s: comma-ok assertions can only be assigned to variables", c.position(stmt))
+		}
+		l := c.scope.loadLocal(ident.Name)
+		c.emitStoreLocal(l)
+	}
+}
+
+// convertTypeSwitch converts a type switch. All the cases are checked in
+// order, with the same checks as the comma-ok type assertions.
+// switch v := x.(type) { ... }
+func (c *codegen) convertTypeSwitch(stmt *ast.TypeSwitchStmt) {
+	if stmt.Init != nil {
+		ast.Walk(c, stmt.Init)
+	}
+
+	// Store the value we switch on into the variable bound by the switch.
+	// If there is no such variable a hidden local is used, so the value
+	// is only evaluated once.
+	var (
+		expr ast.Expr
+		name string
+	)
+	switch t := stmt.Assign.(type) {
+	case *ast.AssignStmt:
+		expr = t.Rhs[0].(*ast.TypeAssertExpr).X
+		name = t.Lhs[0].(*ast.Ident).Name
+	case *ast.ExprStmt:
+		expr = t.X.(*ast.TypeAssertExpr).X
+	}
+	ast.Walk(c, expr)
+	pos := c.scope.newLocal(name)
+	c.emitStoreLocal(pos)
+
+	var (
+		end         = c.newLabel()
+		bodies      = make([]int, len(stmt.Body.List))
+		lDefault    = end
+		matchedType ast.Expr
+	)
+	for i, stmt := range stmt.Body.List {
+		clause := stmt.(*ast.CaseClause)
+		bodies[i] = c.newLabel()
+		if clause.List == nil {
+			lDefault = bodies[i]
+			continue
+		}
+		for _, expr := range clause.List {
+			if matchedType != nil {
+				log.Fatalf("%s: case %s is indistinguishable from case %s at runtime",
+					c.position(expr), c.typeInfo.TypeOf(expr), c.typeInfo.TypeOf(matchedType))
+			}
+			if check, _ := runtimeTypeCheck(c.typeInfo.TypeOf(expr)); check == checkAlways {
+				// This case matches anything that is left.
+				emitJmp(c.prog, vm.JMP, int16(bodies[i]))
+				matchedType = expr
+				continue
+			}
+			c.emitLoadLocalPos(pos)
+			c.emitTypeCheck(expr, c.typeInfo.TypeOf(expr))
+			emitJmp(c.prog, vm.JMPIF, int16(bodies[i]))
+		}
+	}
+	if matchedType == nil {
+		emitJmp(c.prog, vm.JMP, int16(lDefault))
+	}
+
+	for i, stmt := range stmt.Body.List {
+		clause := stmt.(*ast.CaseClause)
+		c.setLabel(bodies[i])
+		for _, stmt := range clause.Body {
+			ast.Walk(c, stmt)
+		}
+		emitJmp(c.prog, vm.JMP, int16(end))
+	}
+	c.setLabel(end)
+}
+
+// emitTypeCheck consumes the value on top of the stack and pushes whether
+// it can be of the given type, see runtimeTypeCheck.
+func (c *codegen) emitTypeCheck(expr ast.Expr, typ types.Type) {
+	check, size := runtimeTypeCheck(typ)
+	switch check {
+	case checkAlways:
+		emitOpcode(c.prog, vm.DROP)
+		emitBool(c.prog, true)
+	case checkSize:
+		// ARRAYSIZE counts the items of arrays, structs and maps and the bytes
+		// of integers and booleans, but it FAULTs on interop interfaces, like
+		// a storage context, which the VM can not tell apart beforehand.
+		emitOpcode(c.prog, vm.ARRAYSIZE)
+		emitInt(c.prog, int64(size))
+		emitOpcode(c.prog, vm.NUMEQUAL)
+	default:
+		log.Fatalf("%s: type %s can not be checked at runtime, only byte slices and arrays, strings, integers and booleans can",
+			c.position(expr), typ)
+	}
+}
+
+// position returns the position of the given node in the source files.
+func (c *codegen) position(node ast.Node) token.Position {
+	return c.buildInfo.program.Fset.Position(node.Pos())
+}
+
+// emitCheckSize checks that the byte array on top of the stack has the
+// given size, the VM will FAULT if it has not.
+func (c *codegen) emitCheckSize(size int) {
+	emitOpcode(c.prog, vm.DUP)
+	emitOpcode(c.prog, vm.SIZE)
+	emitInt(c.prog, int64(size))
+	emitOpcode(c.prog, vm.NUMEQUAL)
+	emitOpcode(c.prog, vm.THROWIFNOT)
+}
+
+// emitDefault loads the zero value of the given type.
+func (c *codegen) emitDefault(typ types.Type) {
+	switch t := typ.Underlying().(type) {
+	case *types.Basic:
+		info := t.Info()
+		switch {
+		case info&types.IsString != 0:
+			emitString(c.prog, "")
+		case info&types.IsBoolean != 0:
+			emitBool(c.prog, false)
+		case t.Kind() == types.Byte:
+			emitBytes(c.prog, []byte{0})
+		case info&types.IsInteger != 0:
+			emitInt(c.prog, 0)
+		default:
+			log.Fatalf("compiler don't know the zero value of type: %s", t)
+		}
+	case *types.Array, *types.Slice:
+		if size, ok := byteArrayLen(t); ok {
+			emitBytes(c.prog, make([]byte, size))
+		} else if isByteType(t) {
+			emitBytes(c.prog, []byte{})
+		} else if arr, ok := t.(*types.Array); ok {
+			emitInt(c.prog, arr.Len())
+			emitOpcode(c.prog, vm.NEWARRAY)
+		} else {
+			emitInt(c.prog, 0)
+			emitOpcode(c.prog, vm.NEWARRAY)
+		}
+	case *types.Struct:
+		emitInt(c.prog, int64(t.NumFields()))
+		emitOpcode(c.prog, vm.NEWSTRUCT)
+		for i := 0; i < t.NumFields(); i++ {
+			emitOpcode(c.prog, vm.DUP)
+			emitInt(c.prog, int64(i))
+			c.emitDefault(t.Field(i).Type())
+			emitOpcode(c.prog, vm.SETITEM)
+		}
+	default:
+		// Interfaces, maps and pointers are zero initialized as an empty
+		// byte array, which is the closest thing to nil the VM has.
+		emitBytes(c.prog, []byte{})
+	}
+}
+
 func (c *codegen) convertStruct(lit *ast.CompositeLit) {
-	// Create a new structScope to initialize and store
-	// the positions of its variables.
 	strct, ok := c.typeInfo.TypeOf(lit).Underlying().(*types.Struct)
 	if !ok {
 		log.Fatalf("the given literal is not of type struct: %v", lit)
@@ -650,36 +1274,23 @@ func (c *codegen) convertStruct(lit *ast.CompositeLit) {
 	emitOpcode(c.prog, vm.NOP)
 	emitInt(c.prog, int64(strct.NumFields()))
 	emitOpcode(c.prog, vm.NEWSTRUCT)
-	emitOpcode(c.prog, vm.TOALTSTACK)
 
-	// We need to locally store all the fields, even if they are not initialized.
+	// We need to store all the fields, even if they are not initialized.
 	// We will initialize all fields to their "zero" value.
 	for i := 0; i < strct.NumFields(); i++ {
 		sField := strct.Field(i)
-		fieldAdded := false
+
+		emitOpcode(c.prog, vm.DUP)
+		emitInt(c.prog, int64(i))
 
 		// Fields initialized by the program.
-		for _, field := range lit.Elts {
-			f := field.(*ast.KeyValueExpr)
-			fieldName := f.Key.(*ast.Ident).Name
-
-			if sField.Name() == fieldName {
-				ast.Walk(c, f.Value)
-				pos := indexOfStruct(strct, fieldName)
-				c.emitStoreLocal(pos)
-				fieldAdded = true
-				break
-			}
-		}
-		if fieldAdded {
-			continue
+		if value := fieldValue(lit, strct, i); value != nil {
+			ast.Walk(c, value)
+		} else {
+			c.emitDefault(sField.Type())
 		}
-
-		typeAndVal := typeAndValueForField(sField)
-		c.emitLoadConst(typeAndVal)
-		c.emitStoreLocal(i)
+		emitOpcode(c.prog, vm.SETITEM)
 	}
-	emitOpcode(c.prog, vm.FROMALTSTACK)
 }
 
 func (c *codegen) convertToken(tok token.Token) {
@@ -739,10 +1350,47 @@ func (c *codegen) convertToken(tok token.Token) {
 
 func (c *codegen) newFunc(decl *ast.FuncDecl) *funcScope {
 	f := newFuncScope(decl, c.newLabel())
-	c.funcs[f.name] = f
+	f.builtin = interopBuiltins[c.funcNameOf(decl)]
+	// Stubs of syscalls and other directives also return constants.
+	if !isInlined(f) && !f.appcall {
+		f.value = c.constResult(decl)
+	}
+	c.funcs[c.funcNameOf(decl)] = f
+	if f.event != "" {
+		c.registerEvent(f)
+	}
 	return f
 }
 
+// constResult returns the result of the given function if it has no
+// parameters and only returns a constant, like runtime.Application().
+// Calls to these functions are replaced by the constant.
+func (c *codegen) constResult(decl *ast.FuncDecl) types.TypeAndValue {
+	if decl.Recv != nil || decl.Type.Params.NumFields() > 0 || decl.Body == nil || len(decl.Body.List) != 1 {
+		return types.TypeAndValue{}
+	}
+	ret, ok := decl.Body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return types.TypeAndValue{}
+	}
+	return c.typeInfo.Types[ret.Results[0]]
+}
+
+// funcNameOf returns the qualified name of the given function declaration.
+func (c *codegen) funcNameOf(decl *ast.FuncDecl) string {
+	return c.funcNameOfIdent(decl.Name)
+}
+
+// funcNameOfIdent returns the qualified name of the function the given
+// identifier refers to.
+func (c *codegen) funcNameOfIdent(ident *ast.Ident) string {
+	fun, ok := c.typeInfo.ObjectOf(ident).(*types.Func)
+	if !ok {
+		return ident.Name
+	}
+	return funcName(fun)
+}
+
 // CodeGen is the function that compiles the program to bytecode.
 func CodeGen(info *buildInfo) (*bytes.Buffer, error) {
 	pkg := info.program.Package(info.initialPackage)
@@ -751,8 +1399,10 @@ func CodeGen(info *buildInfo) (*bytes.Buffer, error) {
 		prog:      new(bytes.Buffer),
 		l:         []int{},
 		funcs:     map[string]*funcScope{},
+		events:    map[string]*funcScope{},
 		typeInfo:  &pkg.Info,
 	}
+	info.debugInfo = &DebugInfo{}
 
 	// Resolve the entrypoint of the program
 	main, mainFile := resolveEntryPoint(mainIdent, pkg)
@@ -764,12 +1414,14 @@ func CodeGen(info *buildInfo) (*bytes.Buffer, error) {
 
 	// Bring all imported functions into scope
 	for _, pkg := range info.program.AllPackages {
+		c.typeInfo = &pkg.Info
 		for _, f := range pkg.Files {
 			c.resolveFuncDecls(f)
 		}
 	}
 
 	// convert the entry point first
+	c.typeInfo = &pkg.Info
 	c.convertFuncDecl(mainFile, main)
 
 	// Generate the code for the program
@@ -792,9 +1444,34 @@ func CodeGen(info *buildInfo) (*bytes.Buffer, error) {
 
 	c.writeJumps()
 
+	if err := c.checkVersion(); err != nil {
+		return nil, err
+	}
 	return c.prog, nil
 }
 
+// checkVersion returns an error if the program uses an instruction that the
+// version of the VM it is compiled for does not have.
+func (c *codegen) checkVersion() error {
+	instrs, err := vm.Disassemble(c.prog.Bytes())
+	if err != nil {
+		return err
+	}
+	for _, instr := range instrs {
+		if instr.Op.AvailableIn(c.buildInfo.version) {
+			continue
+		}
+		err := fmt.Errorf("%s is not available in version %s of the VM", instr.Op, c.buildInfo.version)
+		if sp, ok := c.buildInfo.debugInfo.SequencePoint(instr.Offset); ok && sp.File == "" {
+			err = fmt.Errorf("line %d: %v", sp.Line, err)
+		} else if ok {
+			err = fmt.Errorf("%s:%d: %v", sp.File, sp.Line, err)
+		}
+		return err
+	}
+	return nil
+}
+
 func (c *codegen) resolveFuncDecls(f *ast.File) {
 	for _, decl := range f.Decls {
 		switch n := decl.(type) {
@@ -806,21 +1483,37 @@ func (c *codegen) resolveFuncDecls(f *ast.File) {
 	}
 }
 
+// writeJumps replaces the labels of all jump and call instructions with the
+// offset to their destination. Operands of other instructions are skipped,
+// so data that happens to look like a jump is left untouched.
 func (c *codegen) writeJumps() {
 	b := c.prog.Bytes()
-	for i, op := range b {
+	for i := 0; i < len(b); {
+		op := vm.Instruction(b[i])
 		j := i + 1
-		switch vm.Instruction(op) {
-		case vm.JMP, vm.JMPIFNOT, vm.JMPIF, vm.CALL:
-			index := int16(binary.LittleEndian.Uint16(b[j : j+2]))
-			if int(index) > len(c.l) || int(index) < 0 {
-				continue
+		switch {
+		case op >= vm.PUSHBYTES1 && op <= vm.PUSHBYTES75:
+			i = j + int(op)
+		case op == vm.PUSHDATA1:
+			i = j + 1 + int(b[j])
+		case op == vm.PUSHDATA2:
+			i = j + 2 + int(binary.LittleEndian.Uint16(b[j:j+2]))
+		case op == vm.PUSHDATA4:
+			i = j + 4 + int(binary.LittleEndian.Uint32(b[j:j+4]))
+		case op == vm.SYSCALL:
+			i = j + 1 + int(b[j])
+		case op == vm.APPCALL || op == vm.TAILCALL:
+			i = j + 20
+		case isInstrJmp(op):
+			index := int(binary.LittleEndian.Uint16(b[j : j+2]))
+			if index >= len(c.l) || c.l[index] < 0 {
+				log.Fatalf("jump to unknown label %d, table list %v", index, c.l)
 			}
-			offset := uint16(c.l[index] - i)
-			if offset < 0 {
-				log.Fatalf("new offset is negative, table list %v", c.l)
-			}
-			binary.LittleEndian.PutUint16(b[j:j+2], offset)
+			offset := c.l[index] - i
+			binary.LittleEndian.PutUint16(b[j:j+2], uint16(int16(offset)))
+			i = j + 2
+		default:
+			i = j
 		}
 	}
 }
@@ -0,0 +1,33 @@
+package compiler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/CityOfZion/neo-storm/vm"
+)
+
+// The compiler does not emit calls with an isolated stack itself, but these
+// are refused too when a script is compiled for a VM without them.
+func TestCheckVersion(t *testing.T) {
+	script := []byte{byte(vm.CALL_I), 0, 0, 5, 0, byte(vm.RET)}
+	for _, version := range []vm.Version{vm.Version20, vm.Version21} {
+		c := &codegen{
+			prog:      bytes.NewBuffer(script),
+			buildInfo: &buildInfo{version: version, debugInfo: &DebugInfo{}},
+		}
+		err := c.checkVersion()
+		if err == nil || !strings.Contains(err.Error(), "CALL_I is not available in version "+version.String()) {
+			t.Fatalf("expected CALL_I to be refused in version %s, got %v", version, err)
+		}
+	}
+
+	c := &codegen{
+		prog:      bytes.NewBuffer(script),
+		buildInfo: &buildInfo{version: vm.Version23, debugInfo: &DebugInfo{}},
+	}
+	if err := c.checkVersion(); err != nil {
+		t.Fatal(err)
+	}
+}
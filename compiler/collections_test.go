@@ -0,0 +1,134 @@
+package compiler_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/CityOfZion/neo-storm/vm"
+)
+
+// The collections are compiled into the contract, the key of a list item is
+// concatenated by an opcode directive.
+func TestStorageCollectionsCompiled(t *testing.T) {
+	src := `package foo
+	import "github.com/CityOfZion/neo-storm/interop/storage"
+	func Main() int {
+		ctx := storage.GetContext()
+		m := storage.NewMap(ctx, "balance")
+		m.Put("alice", 10)
+		c := storage.NewCounter(ctx, "supply")
+		c.Next()
+		l := storage.NewList(ctx, "orders")
+		l.Append(c.Get())
+		return m.GetInt("alice")
+	}`
+	b := compileSource(t, src)
+	if !hasOpcodes(b, vm.SWAP, vm.CAT) {
+		t.Fatalf("expected the item key to be concatenated, got %x", b)
+	}
+	if !bytes.Contains(b, syscall("Neo.Storage.Put")) || !bytes.Contains(b, syscall("Neo.Storage.Get")) {
+		t.Fatalf("expected the storage syscalls to be compiled in, got %x", b)
+	}
+}
+
+// storageOf returns the storage of the only contract that used storage in
+// the environment.
+func storageOf(t *testing.T, env *vm.Environment) map[string][]byte {
+	if len(env.Storage) != 1 {
+		t.Fatalf("expected the storage of 1 contract, got %d", len(env.Storage))
+	}
+	items := map[string][]byte{}
+	for _, storage := range env.Storage {
+		for key, item := range storage {
+			items[key] = item.Value
+		}
+	}
+	return items
+}
+
+func TestStorageCollections(t *testing.T) {
+	src := `package foo
+	import "github.com/CityOfZion/neo-storm/interop/storage"
+	func Main(op string) int {
+		ctx := storage.GetContext()
+		if op == "map" {
+			m := storage.NewMap(ctx, "balance.")
+			m.Put("alice", 5)
+			m.Put("bob", 7)
+			m.Delete("bob")
+			return m.GetInt("alice") + m.GetInt("bob")
+		}
+		if op == "counter" {
+			c := storage.NewCounter(ctx, "count")
+			c.Next()
+			c.Add(40)
+			return c.Next()
+		}
+		if op == "list" {
+			l := storage.NewList(ctx, "list")
+			l.Append("a")
+			l.Append("b")
+			l.Append("c")
+			l.Set(1, "x")
+			return l.Len()
+		}
+		return -1
+	}`
+
+	var cases = []struct {
+		op      string
+		expect  int64
+		storage map[string][]byte
+	}{
+		{"map", 5, map[string][]byte{
+			"balance.alice": {5},
+		}},
+		{"counter", 42, map[string][]byte{
+			"count": {42},
+		}},
+		// The items are stored at the prefix followed by # and the bytes of
+		// their index, which are empty for 0.
+		{"list", 3, map[string][]byte{
+			"list":      {3},
+			"list#":     []byte("a"),
+			"list#\x01": []byte("x"),
+			"list#\x02": []byte("c"),
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.op, func(t *testing.T) {
+			env := vm.NewEnvironment()
+			res := runContractIn(t, env, src, vm.NewByteArray([]byte(c.op)))
+			if res.BigInt().Int64() != c.expect {
+				t.Fatalf("expected %d, got %s", c.expect, res)
+			}
+			storage := storageOf(t, env)
+			if len(storage) != len(c.storage) {
+				t.Fatalf("expected %d storage items, got %q", len(c.storage), storage)
+			}
+			for key, value := range c.storage {
+				if !bytes.Equal(storage[key], value) {
+					t.Fatalf("expected %x at %q, got %x", value, key, storage[key])
+				}
+			}
+		})
+	}
+}
+
+// A counter keeps its value between invocations.
+func TestStorageCounterRoundTrip(t *testing.T) {
+	src := `package foo
+	import "github.com/CityOfZion/neo-storm/interop/storage"
+	func Main() int {
+		return storage.NewCounter(storage.GetContext(), "count").Next()
+	}`
+	env := vm.NewEnvironment()
+	for i := int64(1); i <= 3; i++ {
+		if res := runContractIn(t, env, src).BigInt().Int64(); res != i {
+			t.Fatalf("expected %d, got %d", i, res)
+		}
+	}
+	if value := storageOf(t, env)["count"]; !bytes.Equal(value, []byte{3}) {
+		t.Fatalf("expected the counter to be stored as 03, got %x", value)
+	}
+}
@@ -3,9 +3,11 @@ package compiler
 import (
 	"bytes"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"go/ast"
 	"go/build"
+	"go/constant"
 	"go/parser"
 	"go/types"
 	"io"
@@ -13,7 +15,6 @@ import (
 	"log"
 	"os"
 	"strings"
-	"text/tabwriter"
 
 	"github.com/CityOfZion/neo-storm/vm"
 	"golang.org/x/tools/go/loader"
@@ -29,40 +30,110 @@ type Options struct {
 	// The name of the output file.
 	Outfile string
 
-	// Debug will output an hex encoded string of the generated bytecode.
+	// Debug will also write the debug information of the contract to a
+	// .debug.json file, see DebugInfo.
 	Debug bool
+
+	// ABI will also write the NEP-3 ABI of the contract to a .abi.json file.
+	ABI bool
+
+	// Trigger compiles the contract for a single trigger: verification,
+	// verificationR, application or applicationR. The trigger checks are
+	// evaluated at compile time and the code of other triggers is left out.
+	Trigger string
+
+	// VMVersion is the version of the VM the contract is compiled for,
+	// like "2.0". Instructions that version does not have are refused.
+	// Defaults to the latest version.
+	VMVersion string
 }
 
 type buildInfo struct {
 	initialPackage string
 	program        *loader.Program
+
+	// dynamicInvoke is set during code generation if the program calls
+	// contracts of which the script hash is only known at runtime.
+	dynamicInvoke bool
+
+	// events are the events the program declares with the //neo:event
+	// directive, in the order of their declaration.
+	events []Event
+
+	// trigger is the value of the trigger the program is compiled for,
+	// nil if the program can be invoked with any trigger.
+	trigger constant.Value
+
+	// debugInfo maps the generated bytecode back to the source, collected
+	// during code generation.
+	debugInfo *DebugInfo
+
+	// version is the version of the VM the program is compiled for.
+	version vm.Version
 }
 
 // Compile compiles a Go program into bytecode that can run on the NEO virtual machine.
 func Compile(r io.Reader, o *Options) ([]byte, error) {
+	b, _, err := compile(r, o)
+	return b, err
+}
+
+// CompileWithDebugInfo compiles a Go program into bytecode along with the
+// information needed to debug it.
+func CompileWithDebugInfo(r io.Reader, o *Options) ([]byte, *DebugInfo, error) {
+	b, info, err := buildProgram(r, o)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, info.debugInfo, nil
+}
+
+// compile compiles a Go program into bytecode along with its ABI.
+func compile(r io.Reader, o *Options) ([]byte, *ABI, error) {
+	b, info, err := buildProgram(r, o)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, generateABI(info, b), nil
+}
+
+// buildProgram compiles a Go program into bytecode and returns it along with the
+// information collected about the program.
+func buildProgram(r io.Reader, o *Options) ([]byte, *buildInfo, error) {
 	conf := loader.Config{ParserMode: parser.ParseComments}
 	f, err := conf.ParseFile("", r)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	conf.CreateFromFiles("", f)
 
 	prog, err := conf.Load()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	ctx := &buildInfo{
 		initialPackage: f.Name.Name,
 		program:        prog,
+		version:        vm.LatestVersion,
+	}
+	if o.VMVersion != "" {
+		if ctx.version, err = vm.ParseVersion(o.VMVersion); err != nil {
+			return nil, nil, err
+		}
+	}
+	if o.Trigger != "" {
+		if ctx.trigger, err = triggerValue(o.Trigger); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	buf, err := CodeGen(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return buf.Bytes(), nil
+	return buf.Bytes(), ctx, nil
 }
 
 type archive struct {
@@ -86,7 +157,7 @@ func CompileAndSave(src string, o *Options) error {
 	if err != nil {
 		return err
 	}
-	b, err = Compile(bytes.NewReader(b), o)
+	b, info, err := buildProgram(bytes.NewReader(b), o)
 	if err != nil {
 		return fmt.Errorf("Error while trying to compile smart contract file: %v", err)
 	}
@@ -94,40 +165,28 @@ func CompileAndSave(src string, o *Options) error {
 	log.Println(hex.EncodeToString(b))
 
 	out := fmt.Sprintf("%s.%s", o.Outfile, o.Ext)
-	return ioutil.WriteFile(out, b, os.ModePerm)
-}
-
-// CompileAndInspect compiles the program and dumps the opcode in a user friendly format.
-func CompileAndInspect(src string) error {
-	b, err := ioutil.ReadFile(src)
-	if err != nil {
-		return err
-	}
-	b, err = Compile(bytes.NewReader(b), &Options{})
-	if err != nil {
+	if err := ioutil.WriteFile(out, b, os.ModePerm); err != nil {
 		return err
 	}
-
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
-	fmt.Fprintln(w, "INDEX\tOPCODE\tDESC\t")
-
-	for i := 0; i <= len(b)-1; {
-		instr := vm.Instruction(b[i])
-		if instr >= vm.PUSHBYTES1 && instr <= vm.PUSHBYTES75 {
-			fmt.Fprintf(w, "%d\t0x%x\t%s\t\n", i, b[i], fmt.Sprintf("PUSHBYTES%d", int(instr)))
-			for x := 0; x < int(instr); x++ {
-				fmt.Fprintf(w, "%d\t0x%x\t%s\t\n", i, b[i+1+x], string(b[i+1+x]))
-			}
-			i += int(instr) + 1
-			continue
+	if o.Debug {
+		if err := writeJSON(fmt.Sprintf("%s.debug.json", o.Outfile), info.debugInfo); err != nil {
+			return err
 		}
-		fmt.Fprintf(w, "%d\t0x%x\t%s\t\n", i, b[i], instr)
-		i++
 	}
-	w.Flush()
+	if o.ABI {
+		return writeJSON(fmt.Sprintf("%s.abi.json", o.Outfile), generateABI(info, b))
+	}
 	return nil
 }
 
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, os.ModePerm)
+}
+
 func gopath() string {
 	gopath := os.Getenv("GOPATH")
 	if len(gopath) == 0 {
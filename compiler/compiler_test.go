@@ -1,12 +1,17 @@
 package compiler_test
 
 import (
+	"bytes"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path"
+	"strings"
 	"testing"
 
 	"github.com/CityOfZion/neo-storm/compiler"
+	"github.com/CityOfZion/neo-storm/vm"
 )
 
 const examplePath = "../examples"
@@ -55,3 +60,178 @@ func compileFile(src string) error {
 	_, err = compiler.Compile(file, &o)
 	return err
 }
+
+// compileSource compiles src and fails the test if it does not compile.
+func compileSource(t *testing.T, src string) []byte {
+	b, err := compiler.Compile(strings.NewReader(src), &compiler.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// hasOpcodes reports whether the script contains the given instructions
+// in a row.
+func hasOpcodes(script []byte, ops ...vm.Instruction) bool {
+	seq := make([]byte, len(ops))
+	for i, op := range ops {
+		seq[i] = byte(op)
+	}
+	return bytes.Contains(script, seq)
+}
+
+// compileError compiles src in another process, as the compiler exits on
+// most errors, and returns what it logged.
+func compileError(t *testing.T, src string) string {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestCompileErrorHelper$")
+	cmd.Env = append(os.Environ(), "STORM_COMPILE_SRC="+src)
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("expected the compilation to fail:\n%s", out)
+	}
+	return string(out)
+}
+
+// TestCompileErrorHelper is the process started by compileError.
+func TestCompileErrorHelper(t *testing.T) {
+	src, ok := os.LookupEnv("STORM_COMPILE_SRC")
+	if !ok {
+		t.Skip("only run by compileError")
+	}
+	if _, err := compiler.Compile(strings.NewReader(src), &compiler.Options{}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// The results of calls that are used as a statement are dropped, calls
+// without a result leave nothing to drop.
+func TestCallStatement(t *testing.T) {
+	var cases = []struct {
+		stmt   string
+		expect []byte
+	}{
+		{"inc(1)", []byte{byte(vm.CALL), 0, 0, byte(vm.DROP)}},
+		{"none()", []byte{byte(vm.CALL), 0, 0, byte(vm.PUSH7)}},
+		{"runtime.Notify(1)", []byte{byte(vm.NOP), byte(vm.PUSH7)}},
+	}
+	for _, c := range cases {
+		src := `package foo
+		import "github.com/CityOfZion/neo-storm/interop/runtime"
+		func Main() int {
+			` + c.stmt + `
+			return 7
+		}
+		func inc(x int) int {
+			return x + 1
+		}
+		func none() {
+			runtime.Notify(2)
+		}`
+		b := compileSource(t, src)
+		// Mask the offsets of the calls.
+		for i := 0; i+2 < len(b); i++ {
+			if vm.Instruction(b[i]) == vm.CALL {
+				b[i+1], b[i+2] = 0, 0
+			}
+		}
+		if !bytes.Contains(b, c.expect) {
+			t.Fatalf("%s: expected %x in %x", c.stmt, c.expect, b)
+		}
+	}
+}
+
+// Dropped results take no locals, Main only needs one for its return value.
+func TestCallStatementLocals(t *testing.T) {
+	src := `package foo
+	func Main() int {
+		inc(1)
+		inc(2)
+		return 7
+	}
+	func inc(x int) int {
+		return x + 1
+	}`
+	b := compileSource(t, src)
+	if !bytes.HasPrefix(b, []byte{byte(vm.PUSH1), byte(vm.NEWARRAY), byte(vm.TOALTSTACK)}) {
+		t.Fatalf("expected 1 local, got %x", b)
+	}
+}
+
+func TestCompileForTrigger(t *testing.T) {
+	src := `package foo
+	import "github.com/CityOfZion/neo-storm/interop/runtime"
+	func Main() bool {
+		trigger := runtime.GetTrigger()
+		if trigger == runtime.Verification() {
+			runtime.Log("verification only")
+			return true
+		}
+		if trigger == runtime.Application() {
+			runtime.Log("application only")
+		}
+		return false
+	}`
+	for trigger, expected := range map[string]string{
+		"verification": "verification only",
+		"application":  "application only",
+	} {
+		b, err := compiler.Compile(strings.NewReader(src), &compiler.Options{Trigger: trigger})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(b, []byte(expected)) {
+			t.Fatalf("expected %s script to contain %q", trigger, expected)
+		}
+		if bytes.Count(b, []byte("only")) != 1 {
+			t.Fatalf("expected %s script to only contain the code of its trigger", trigger)
+		}
+	}
+
+	if _, err := compiler.Compile(strings.NewReader(src), &compiler.Options{Trigger: "unknown"}); err == nil {
+		t.Fatal("expected an error for an unknown trigger")
+	}
+}
+
+func TestCompileForVMVersion(t *testing.T) {
+	var cases = []struct {
+		name   string
+		src    string
+		expect string
+	}{
+		{
+			"map",
+			`package foo
+			func Main() int {
+				m := newMap()
+				return len(m)
+			}
+
+			//neo:opcode NEWMAP
+			func newMap() []interface{} {
+				return nil
+			}`,
+			"line 3: NEWMAP is not available in version 2.0",
+		},
+		{
+			"verify",
+			`package foo
+			import "github.com/CityOfZion/neo-storm/interop/crypto"
+			func Main(msg, sig, pubkey []byte) bool {
+				return crypto.VerifySignature(msg, sig, pubkey)
+			}`,
+			"line 4: VERIFY is not available in version 2.0",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := compiler.Compile(strings.NewReader(c.src), &compiler.Options{VMVersion: "2.1"}); err != nil {
+				t.Fatal(err)
+			}
+			_, err := compiler.Compile(strings.NewReader(c.src), &compiler.Options{VMVersion: "2.0"})
+			if err == nil || !strings.Contains(err.Error(), c.expect) {
+				t.Fatalf("expected %q, got %v", c.expect, err)
+			}
+		})
+	}
+}
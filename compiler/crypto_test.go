@@ -0,0 +1,158 @@
+package compiler_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/CityOfZion/neo-storm/vm"
+)
+
+type testKey struct {
+	priv   *ecdsa.PrivateKey
+	pubkey []byte
+}
+
+func newTestKey(t *testing.T) testKey {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKey{priv: priv, pubkey: elliptic.MarshalCompressed(elliptic.P256(), priv.X, priv.Y)}
+}
+
+// sign returns the signature of msg in the format of the VM, r and s as 32
+// bytes each.
+func (k testKey) sign(t *testing.T, msg []byte) []byte {
+	h := sha256.Sum256(msg)
+	r, s, err := ecdsa.Sign(rand.Reader, k.priv, h[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := make([]byte, 64)
+	rb, sb := r.Bytes(), s.Bytes()
+	copy(sig[32-len(rb):], rb)
+	copy(sig[64-len(sb):], sb)
+	return sig
+}
+
+func bytesItems(bs ...[]byte) []vm.StackItem {
+	items := make([]vm.StackItem, len(bs))
+	for i, b := range bs {
+		items[i] = vm.NewByteArray(b)
+	}
+	return items
+}
+
+func TestVerifySignature(t *testing.T) {
+	src := `package foo
+	import "github.com/CityOfZion/neo-storm/interop/crypto"
+	func Main(msg, sig, pubkey []byte) bool {
+		return crypto.VerifySignature(msg, sig, pubkey)
+	}`
+	key := newTestKey(t)
+	msg := []byte("hello")
+	sig := key.sign(t, msg)
+
+	if !runContract(t, src, bytesItems(msg, sig, key.pubkey)...).Bool() {
+		t.Fatal("expected the signature to be valid")
+	}
+	if runContract(t, src, bytesItems([]byte("other"), sig, key.pubkey)...).Bool() {
+		t.Fatal("expected the signature of another message to be invalid")
+	}
+}
+
+func TestCheckSig(t *testing.T) {
+	src := `package foo
+	import "github.com/CityOfZion/neo-storm/interop/crypto"
+	func Main(pubkey, sig []byte) bool {
+		return crypto.CheckSig(pubkey, sig)
+	}`
+	key := newTestKey(t)
+	msg := []byte("transaction")
+
+	v := loadContract(t, nil, src, bytesItems(key.pubkey, key.sign(t, msg))...)
+	v.SetMessage(msg)
+	if err := v.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !v.Estack().Pop().Bool() {
+		t.Fatal("expected the signature to be valid")
+	}
+}
+
+func TestCheckMultiSig(t *testing.T) {
+	src := `package foo
+	import "github.com/CityOfZion/neo-storm/interop/crypto"
+	func Main(k1, k2, k3, s1, s2 []byte) bool {
+		return crypto.CheckMultiSig([][]byte{k1, k2, k3}, [][]byte{s1, s2})
+	}`
+	keys := []testKey{newTestKey(t), newTestKey(t), newTestKey(t)}
+	msg := []byte("transaction")
+	sig1, sig3 := keys[0].sign(t, msg), keys[2].sign(t, msg)
+
+	var cases = []struct {
+		name   string
+		sigs   [][]byte
+		expect bool
+	}{
+		{"in the order of the keys", [][]byte{sig1, sig3}, true},
+		{"in another order", [][]byte{sig3, sig1}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			args := bytesItems(keys[0].pubkey, keys[1].pubkey, keys[2].pubkey, c.sigs[0], c.sigs[1])
+			v := loadContract(t, nil, src, args...)
+			v.SetMessage(msg)
+			if err := v.Run(); err != nil {
+				t.Fatal(err)
+			}
+			if res := v.Estack().Pop().Bool(); res != c.expect {
+				t.Fatalf("expected %t, got %t", c.expect, res)
+			}
+		})
+	}
+}
+
+// Functions of the program are not mistaken for the crypto functions with
+// the same name.
+func TestCryptoFuncNames(t *testing.T) {
+	src := `package foo
+	func Main() int {
+		return CheckSig(2, 3) + VerifySignature(1)
+	}
+	func CheckSig(a, b int) int {
+		return a*10 + b
+	}
+	func VerifySignature(a int) int {
+		return a * 100
+	}`
+	if res := runContract(t, src).BigInt().Int64(); res != 123 {
+		t.Fatalf("expected 123, got %d", res)
+	}
+}
+
+// The crypto functions are compiled to their opcode instead of a syscall.
+func TestCryptoOpcodes(t *testing.T) {
+	var cases = []struct {
+		call string
+		op   vm.Instruction
+	}{
+		{"crypto.VerifySignature(a, b, c)", vm.VERIFY},
+		{"crypto.CheckSig(a, b)", vm.CHECKSIG},
+		{"crypto.CheckMultiSig([][]byte{a}, [][]byte{b})", vm.CHECKMULTISIG},
+	}
+	for _, c := range cases {
+		src := `package foo
+		import "github.com/CityOfZion/neo-storm/interop/crypto"
+		func Main(a, b, c []byte) bool {
+			return ` + c.call + `
+		}`
+		b := compileSource(t, src)
+		if !hasOpcodes(b, c.op) || hasOpcodes(b, vm.SYSCALL) {
+			t.Fatalf("%s: expected %s without a syscall, got %x", c.call, c.op, b)
+		}
+	}
+}
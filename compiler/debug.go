@@ -0,0 +1,108 @@
+package compiler
+
+import (
+	"go/ast"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DebugInfo maps the bytecode of a program back to its Go source, it is
+// written to a .debug.json file when compiling in debug mode and used by the
+// debugger to show where the VM is and which values the variables hold.
+type DebugInfo struct {
+	SequencePoints []SequencePoint     `json:"sequencepoints"`
+	Functions      []FunctionDebugInfo `json:"functions"`
+}
+
+// SequencePoint marks the first instruction of a statement.
+type SequencePoint struct {
+	Offset int `json:"offset"`
+	// File is empty for the file the program is compiled from.
+	File string `json:"file,omitempty"`
+	Line int    `json:"line"`
+}
+
+// FunctionDebugInfo holds the bytecode range of a function, [Start, End),
+// and the positions of its local variables in the array it keeps on the
+// alt stack.
+type FunctionDebugInfo struct {
+	Name   string         `json:"name"`
+	File   string         `json:"file,omitempty"`
+	Start  int            `json:"start"`
+	End    int            `json:"end"`
+	Locals map[string]int `json:"locals"`
+}
+
+// SequencePoint returns the sequence point of the statement the instruction
+// at the given offset belongs to.
+func (d *DebugInfo) SequencePoint(offset int) (SequencePoint, bool) {
+	i := sort.Search(len(d.SequencePoints), func(i int) bool {
+		return d.SequencePoints[i].Offset > offset
+	})
+	if i == 0 {
+		return SequencePoint{}, false
+	}
+	return d.SequencePoints[i-1], true
+}
+
+// Offsets returns the offsets of the statements on the given line. The file
+// is matched against the end of the recorded paths, an empty file is the
+// file the program is compiled from.
+func (d *DebugInfo) Offsets(file string, line int) []int {
+	var offsets []int
+	for _, sp := range d.SequencePoints {
+		if sp.Line == line && matchFile(sp.File, file) {
+			offsets = append(offsets, sp.Offset)
+		}
+	}
+	return offsets
+}
+
+// Function returns the function the instruction at the given offset belongs to.
+func (d *DebugInfo) Function(offset int) *FunctionDebugInfo {
+	for i := range d.Functions {
+		f := &d.Functions[i]
+		if offset >= f.Start && offset < f.End {
+			return f
+		}
+	}
+	return nil
+}
+
+func matchFile(path, file string) bool {
+	if path == "" || file == "" {
+		return path == file
+	}
+	return path == file || strings.HasSuffix(path, string(filepath.Separator)+file)
+}
+
+// addSequencePoint records the start of the given statement at the current
+// program offset. A statement emitted at the same offset as the previous
+// one, like the init statement of a for loop, replaces it.
+func (c *codegen) addSequencePoint(node ast.Node) {
+	pos := c.position(node)
+	sp := SequencePoint{Offset: c.prog.Len(), File: pos.Filename, Line: pos.Line}
+	d := c.buildInfo.debugInfo
+	if n := len(d.SequencePoints); n > 0 && d.SequencePoints[n-1].Offset == sp.Offset {
+		d.SequencePoints[n-1] = sp
+		return
+	}
+	d.SequencePoints = append(d.SequencePoints, sp)
+}
+
+// addFunction records the bytecode range and the locals of a converted function.
+func (c *codegen) addFunction(f *funcScope, start int) {
+	locals := make(map[string]int, len(f.locals))
+	for name, i := range f.locals {
+		locals[name] = i
+	}
+	d := c.buildInfo.debugInfo
+	d.Functions = append(d.Functions, FunctionDebugInfo{
+		Name:   f.name,
+		File:   c.position(f.decl).Filename,
+		Start:  start,
+		End:    c.prog.Len(),
+		Locals: locals,
+	})
+}
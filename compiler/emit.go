@@ -5,7 +5,6 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"math/big"
 
 	"github.com/CityOfZion/neo-storm/vm"
 )
@@ -41,9 +40,24 @@ func emitInt(w *bytes.Buffer, i int64) error {
 		return emitOpcode(w, val)
 	}
 
-	bInt := big.NewInt(i)
-	val := arrayReverse(bInt.Bytes())
-	return emitBytes(w, val)
+	return emitBytes(w, intBytes(i))
+}
+
+// intBytes returns the shortest little endian two's complement encoding of
+// i, the way the VM reads integers from byte arrays.
+func intBytes(i int64) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, uint64(i))
+	n := len(b)
+	for n > 1 {
+		last, sign := b[n-1], b[n-2]&0x80
+		if (last == 0 && sign == 0) || (last == 0xff && sign != 0) {
+			n--
+			continue
+		}
+		break
+	}
+	return b[:n]
 }
 
 func emitString(w *bytes.Buffer, s string) error {
@@ -86,6 +100,15 @@ func emitSyscall(w *bytes.Buffer, api string) error {
 	return emit(w, vm.SYSCALL, buf)
 }
 
+// emitAppCall emits an APPCALL of the contract with the given script hash.
+// A zero hash calls the contract of which the hash is on top of the stack.
+func emitAppCall(w *bytes.Buffer, scriptHash []byte) error {
+	if len(scriptHash) != 20 {
+		return errors.New("script hash of an appcall must be 20 bytes")
+	}
+	return emit(w, vm.APPCALL, scriptHash)
+}
+
 func emitCall(w *bytes.Buffer, instr vm.Instruction, label int16) error {
 	return emitJmp(w, instr, label)
 }
@@ -36,3 +36,25 @@ func TestArrayReverse(t *testing.T) {
 		}
 	}
 }
+
+func TestIntBytes(t *testing.T) {
+	var cases = []struct {
+		actual int64
+		expect []byte
+	}{
+		{actual: 16, expect: []byte{0x10}},
+		{actual: 127, expect: []byte{0x7f}},
+		{actual: 128, expect: []byte{0x80, 0x00}},
+		{actual: 10000000, expect: []byte{0x80, 0x96, 0x98, 0x00}},
+		{actual: -2, expect: []byte{0xfe}},
+		{actual: -128, expect: []byte{0x80}},
+		{actual: -129, expect: []byte{0x7f, 0xff}},
+	}
+
+	for _, item := range cases {
+		res := intBytes(item.actual)
+		if !bytes.Equal(res, item.expect) {
+			t.Fatalf("intBytes(%d) works wrong:\n \t actual: %#v \n \t expect: %#v", item.actual, res, item.expect)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package compiler_test
+
+import (
+	"strings"
+	"testing"
+)
+
+// Events are checked where they are declared, also if they are never called.
+func TestEventDeclaration(t *testing.T) {
+	var cases = []struct {
+		name   string
+		decl   string
+		expect string
+	}{
+		{
+			"body",
+			`//neo:event transfer
+			func OnTransfer(amount int) {
+				amount++
+			}`,
+			"event OnTransfer must have an empty body and no results",
+		},
+		{
+			"duplicate",
+			`//neo:event transfer
+			func OnTransfer(amount int) {}
+			//neo:event transfer
+			func OnSend(amount int) {}`,
+			"event transfer is already declared by OnTransfer",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			src := `package foo
+			` + c.decl + `
+			func Main() int {
+				return 1
+			}`
+			if out := compileError(t, src); !strings.Contains(out, c.expect) {
+				t.Fatalf("expected %q, got:\n%s", c.expect, out)
+			}
+		})
+	}
+}
@@ -2,6 +2,9 @@ package compiler
 
 import (
 	"go/ast"
+	"go/types"
+
+	"github.com/CityOfZion/neo-storm/vm"
 )
 
 // A funcScope represents the scope within the function context.
@@ -10,9 +13,28 @@ type funcScope struct {
 	// identifier of the function.
 	name string
 
-	// Selector of the function if there is any. Only functions imported
-	// from other packages should have a selector.
-	selector *ast.Ident
+	// The syscall API this function is bound to with a //neo:syscall
+	// directive. Empty if this is a regular function.
+	syscall string
+
+	// Opcodes this function is compiled to with a //neo:opcode directive.
+	opcodes []vm.Instruction
+
+	// Name of the event this function declares with a //neo:event
+	// directive. Empty if this is not an event.
+	event string
+
+	// The constant this function returns, see codegen.constResult.
+	// The Value is nil if the function does not return a constant.
+	value types.TypeAndValue
+
+	// True if calls to this function are compiled to an APPCALL,
+	// see appcallDirective.
+	appcall bool
+
+	// True if calls to this function are compiled by the compiler itself,
+	// see interopBuiltins.
+	builtin bool
 
 	// The declaration of the function in the AST. Nil if this scope is not a function.
 	decl *ast.FuncDecl
@@ -23,52 +45,22 @@ type funcScope struct {
 	// Local variables
 	locals map[string]int
 
-	// voidCalls are basically functions that return their value
-	// into nothing. The stack has their return value but there
-	// is nothing that consumes it. We need to keep track of
-	// these functions so we can cleanup (drop) the returned
-	// value from the stack. We also need to add every voidCall
-	// return value to the stack size.
-	voidCalls map[*ast.CallExpr]bool
-
 	// local variable counter
 	i int
 }
 
 func newFuncScope(decl *ast.FuncDecl, label int) *funcScope {
 	return &funcScope{
-		name:      decl.Name.Name,
-		decl:      decl,
-		label:     label,
-		locals:    map[string]int{},
-		voidCalls: map[*ast.CallExpr]bool{},
-		i:         -1,
-	}
-}
-
-// analyzeVoidCalls will check for functions that are not assigned
-// and therefore we need to cleanup the return value from the stack.
-func (c *funcScope) analyzeVoidCalls(node ast.Node) bool {
-	switch n := node.(type) {
-	case *ast.AssignStmt:
-		for i := 0; i < len(n.Rhs); i++ {
-			switch n.Rhs[i].(type) {
-			case *ast.CallExpr:
-				return false
-			}
-		}
-	case *ast.ReturnStmt:
-		switch n.Results[0].(type) {
-		case *ast.CallExpr:
-			return false
-		}
-	case *ast.BinaryExpr:
-		return false
-	case *ast.CallExpr:
-		c.voidCalls[n] = true
-		return false
+		name:    decl.Name.Name,
+		decl:    decl,
+		syscall: directiveArg(decl, syscallDirectivePrefix),
+		event:   directiveArg(decl, eventDirectivePrefix),
+		opcodes: opcodeDirective(decl),
+		appcall: hasDirective(decl, appcallDirective),
+		label:   label,
+		locals:  map[string]int{},
+		i:       -1,
 	}
-	return true
 }
 
 func (c *funcScope) stackSize() int64 {
@@ -76,27 +68,26 @@ func (c *funcScope) stackSize() int64 {
 	ast.Inspect(c.decl, func(n ast.Node) bool {
 		switch n := n.(type) {
 		case *ast.AssignStmt:
-			size += len(n.Rhs)
-		case *ast.ReturnStmt, *ast.IfStmt:
+			size += len(n.Lhs)
+		case *ast.ReturnStmt, *ast.IfStmt, *ast.TypeSwitchStmt:
 			size++
-		// This handles the inline GenDecl like "var x = 2"
+		// This handles the inline GenDecl like "var x = 2" and "var x [20]byte"
 		case *ast.GenDecl:
-			switch t := n.Specs[0].(type) {
-			case *ast.ValueSpec:
-				if len(t.Values) > 0 {
-					size++
+			for _, spec := range n.Specs {
+				if t, ok := spec.(*ast.ValueSpec); ok {
+					size += len(t.Names)
 				}
 			}
 		}
 		return true
 	})
 
-	numArgs := len(c.decl.Type.Params.List)
+	numArgs := c.decl.Type.Params.NumFields()
 	// Also take care of struct methods recv: e.g. (t Token).Foo().
 	if c.decl.Recv != nil {
 		numArgs += len(c.decl.Recv.List)
 	}
-	return int64(size + numArgs + len(c.voidCalls))
+	return int64(size + numArgs)
 }
 
 // newLocal creates a new local variable into the scope of the function.
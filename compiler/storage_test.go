@@ -0,0 +1,162 @@
+package compiler_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/CityOfZion/neo-storm/vm"
+)
+
+// syscall returns the SYSCALL instruction of the given API, followed by
+// the NOP the compiler emits after it.
+func syscall(api string) []byte {
+	b := append([]byte{byte(vm.SYSCALL), byte(len(api))}, api...)
+	return append(b, byte(vm.NOP))
+}
+
+func TestStorageGetters(t *testing.T) {
+	var cases = []struct {
+		call   string
+		expect []byte
+	}{
+		// Missing keys are converted to 0 by adding it.
+		{"storage.GetInt(ctx, key)", append(syscall("Neo.Storage.Get"), byte(vm.PUSH0), byte(vm.ADD))},
+		{"storage.GetBytes(ctx, key)", syscall("Neo.Storage.Get")},
+		{"storage.PutEx(ctx, key, 1, storage.Constant)", syscall("System.Storage.PutEx")},
+		{"ctx.AsReadOnly()", syscall("Neo.StorageContext.AsReadOnly")},
+	}
+	for _, c := range cases {
+		src := `package foo
+		import "github.com/CityOfZion/neo-storm/interop/storage"
+		func Main(ctx storage.Context, key []byte) int {
+			` + c.call + `
+			return 0
+		}`
+		if b := compileSource(t, src); !bytes.Contains(b, c.expect) {
+			t.Fatalf("%s: expected %x in %x", c.call, c.expect, b)
+		}
+	}
+}
+
+func TestStorageStructSyscalls(t *testing.T) {
+	src := `package foo
+	import "github.com/CityOfZion/neo-storm/interop/storage"
+	type order struct {
+		id     int
+		amount int
+	}
+	func Main() int {
+		ctx := storage.GetContext()
+		storage.PutStruct(ctx, "last", order{id: 1, amount: 10})
+		var o order
+		storage.GetStruct(ctx, "last", &o)
+		return o.amount
+	}`
+
+	// The struct is serialized before it is put and deserialized after it
+	// is read.
+	b := compileSource(t, src)
+	put := append(syscall("Neo.Runtime.Serialize"), byte(vm.PUSH2), byte(vm.XSWAP))
+	put = append(put, syscall("Neo.Storage.Put")...)
+	if !bytes.Contains(b, put) {
+		t.Fatalf("expected %x in %x", put, b)
+	}
+	if !bytes.Contains(b, syscall("Neo.Storage.Get")) || !bytes.Contains(b, syscall("Neo.Runtime.Deserialize")) {
+		t.Fatalf("expected the struct to be read and deserialized, got %x", b)
+	}
+}
+
+func TestStorageStruct(t *testing.T) {
+	src := `package foo
+	import "github.com/CityOfZion/neo-storm/interop/storage"
+	type order struct {
+		id     int
+		amount int
+		memo   string
+	}
+	type book struct {
+		last order
+	}
+	func Main(op string) int {
+		ctx := storage.GetContext()
+		storage.PutStruct(ctx, "order", order{id: 2, amount: 20, memo: "first"})
+
+		if op == "roundtrip" {
+			var o order
+			if !storage.GetStruct(ctx, "order", &o) {
+				return -1
+			}
+			if o.memo != "first" {
+				return -2
+			}
+			return o.id*100 + o.amount
+		}
+		if op == "missing" {
+			o := order{id: 7}
+			if storage.GetStruct(ctx, "nothing", &o) {
+				return -1
+			}
+			return o.id
+		}
+		if op == "statement" {
+			var o order
+			storage.GetStruct(ctx, "order", &o)
+			return o.amount
+		}
+		if op == "field" {
+			var b book
+			storage.GetStruct(ctx, "order", &b.last)
+			return b.last.id
+		}
+		return 0
+	}`
+
+	var cases = []struct {
+		op     string
+		expect int64
+	}{
+		{"roundtrip", 220},
+		{"missing", 7},
+		{"statement", 20},
+		{"field", 2},
+	}
+	for _, c := range cases {
+		t.Run(c.op, func(t *testing.T) {
+			res := runContractIn(t, vm.NewEnvironment(), src, vm.NewByteArray([]byte(c.op)))
+			if res.BigInt().Int64() != c.expect {
+				t.Fatalf("expected %d, got %s", c.expect, res)
+			}
+		})
+	}
+}
+
+// Functions of the program are not mistaken for the storage functions with
+// the same name.
+func TestStorageStructFuncNames(t *testing.T) {
+	src := `package foo
+	func Main() int {
+		return GetStruct(1, 2, 3)
+	}
+	func GetStruct(a, b, c int) int {
+		return a + b + c
+	}`
+	if res := runContract(t, src).BigInt().Int64(); res != 6 {
+		t.Fatalf("expected 6, got %d", res)
+	}
+}
+
+func TestGetStructNeedsPointer(t *testing.T) {
+	src := `package foo
+	import "github.com/CityOfZion/neo-storm/interop/storage"
+	type order struct {
+		id int
+	}
+	func Main() bool {
+		var o order
+		return storage.GetStruct(storage.GetContext(), "order", o)
+	}`
+	if out := compileError(t, src); !strings.Contains(out, "GetStruct needs a pointer to a struct") {
+		t.Fatalf("expected an error about the pointer, got:\n%s", out)
+	}
+}
@@ -0,0 +1,96 @@
+package compiler_test
+
+import (
+	"testing"
+
+	"github.com/CityOfZion/neo-storm/vm"
+)
+
+func TestPromotedFieldPath(t *testing.T) {
+	// Promoted fields are loaded through the structs that embed them,
+	// id is field 0 of base, which is field 1 of named.
+	src := `package foo
+	type base struct {
+		id int
+	}
+	type named struct {
+		name string
+		base
+	}
+	func Main(n named) int {
+		return n.id
+	}`
+	if b := compileSource(t, src); !hasOpcodes(b, vm.PUSH1, vm.PICKITEM, vm.PUSH0, vm.PICKITEM) {
+		t.Fatalf("expected the field to be loaded through base, got %x", b)
+	}
+
+	src = `package foo
+	type base struct {
+		id int
+	}
+	type named struct {
+		name string
+		base
+	}
+	func Main(n named) int {
+		n.id = 3
+		return 0
+	}`
+	if b := compileSource(t, src); !hasOpcodes(b, vm.PUSH1, vm.PICKITEM, vm.PUSH0, vm.ROT, vm.SETITEM) {
+		t.Fatalf("expected the field to be stored in base, got %x", b)
+	}
+}
+
+func TestEmbeddedStruct(t *testing.T) {
+	src := `package foo
+	type base struct {
+		id int
+	}
+	func (b base) double() int {
+		return b.id * 2
+	}
+	type named struct {
+		base
+		name string
+	}
+	type token struct {
+		named
+		supply int
+	}
+	func Main(op string) int {
+		tok := token{named: named{base: base{id: 3}, name: "neo"}, supply: 100}
+		if op == "read" {
+			return tok.id + tok.supply
+		}
+		if op == "write" {
+			tok.name = "gas"
+			tok.id = 7
+			if tok.named.name != "gas" {
+				return -1
+			}
+			return tok.named.base.id
+		}
+		if op == "method" {
+			tok.id = 21
+			return tok.double()
+		}
+		return 0
+	}`
+
+	var cases = []struct {
+		op     string
+		expect int64
+	}{
+		{"read", 103},
+		{"write", 7},
+		{"method", 42},
+	}
+	for _, c := range cases {
+		t.Run(c.op, func(t *testing.T) {
+			res := runContract(t, src, vm.NewByteArray([]byte(c.op)))
+			if res.BigInt().Int64() != c.expect {
+				t.Fatalf("expected %d, got %s", c.expect, res)
+			}
+		})
+	}
+}
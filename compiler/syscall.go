@@ -1,91 +1,37 @@
 package compiler
 
-var syscalls = map[string]map[string]string{
-	"storage": {
-		"GetContext": "Neo.Storage.GetContext",
-		"Put":        "Neo.Storage.Put",
-		"Get":        "Neo.Storage.Get",
-		"Delete":     "Neo.Storage.Delete",
-		"Find":       "Neo.Storage.Find",
-	},
-	"runtime": {
-		"GetTrigger":   "Neo.Runtime.GetTrigger",
-		"CheckWitness": "Neo.Runtime.CheckWitness",
-		"Notify":       "Neo.Runtime.Notify",
-		"Log":          "Neo.Runtime.Log",
-		"GetTime":      "Neo.Runtime.GetTime",
-		"Serialize":    "Neo.Runtime.Serialize",
-		"Deserialize":  "Neo.Runtime.Deserialize",
-	},
-	"blockchain": {
-		"GetHeight":      "Neo.Blockchain.GetHeight",
-		"GetHeader":      "Neo.Blockchain.GetHeader",
-		"GetBlock":       "Neo.Blockchain.GetBlock",
-		"GetTransaction": "Neo.Blockchain.GetTransaction",
-		"GetContract":    "Neo.Blockchain.GetContract",
-		"GetAccount":     "Neo.Blockchain.GetAccount",
-		"GetValidators":  "Neo.Blockchain.GetValidators",
-		"GetAsset":       "Neo.Blockchain.GetAsset",
-	},
-	"header": {
-		"GetIndex":         "Neo.Header.GetIndex",
-		"GetHash":          "Neo.Header.GetHash",
-		"GetPrevHash":      "Neo.Header.GetPrevHash",
-		"GetTimestamp":     "Neo.Header.GetTimestamp",
-		"GetVersion":       "Neo.Header.GetVersion",
-		"GetMerkleRoot":    "Neo.Header.GetMerkleRoot",
-		"GetConsensusData": "Neo.Header.GetConsensusData",
-		"GetNextConsensus": "Neo.Header.GetNextConsensus",
-	},
-	"block": {
-		"GetTransactionCount": "Neo.Block.GetTransactionCount",
-		"GetTransactions":     "Neo.Block.GetTransactions",
-		"GetTransaction":      "Neo.Block.GetTransaction",
-	},
-	"transaction": {
-		"GetHash":         "Neo.Transaction.GetHash",
-		"GetType":         "Neo.Transaction.GetType",
-		"GetAttributes":   "Neo.Transaction.GetAttributes",
-		"GetInputs":       "Neo.Transaction.GetInputs",
-		"GetOutputs":      "Neo.Transaction.GetOutputs",
-		"GetReferences":   "Neo.Transaction.GetReferences",
-		"GetUnspentCoins": "Neo.Transaction.GetUnspentCoins",
-		"GetScript":       "Neo.Transaction.GetScript",
-	},
-	"asset": {
-		"GetAssetID":   "Neo.Asset.GetAssetID",
-		"GetAssetType": "Neo.Asset.GetAssetType",
-		"GetAmount":    "Neo.Asset.GetAmount",
-		"Create":       "Neo.Asset.Create",
-		"Renew":        "Neo.Asset.Renew",
-	},
-	"contract": {
-		"GetScript":         "Neo.Contract.GetScript",
-		"IsPayable":         "Neo.Contract.IsPayable",
-		"Create":            "Neo.Contract.Create",
-		"Destroy":           "Neo.Contract.Destroy",
-		"Migrate":           "Neo.Contract.Migrate",
-		"GetStorageContext": "Neo.Contract.GetStorageContext",
-	},
-	"input": {
-		"GetHash":  "Neo.Input.GetHash",
-		"GetIndex": "Neo.Input.GetIndex",
-	},
-	"output": {
-		"GetAssetID":    "Neo.Output.GetAssetID",
-		"GetValue":      "Neo.Output.GetValue",
-		"GetScriptHash": "Neo.Output.GetScriptHash",
-	},
-	"engine": {
-		"GetScriptContainer":     "System.ExecutionEngine.GetScriptContainer",
-		"GetCallingScriptHash":   "System.ExecutionEngine.GetCallingScriptHash",
-		"GetEntryScriptHash":     "System.ExecutionEngine.GetEntryScriptHash",
-		"GetExecutingScriptHash": "System.ExecutionEngine.GetExecutingScriptHash",
-	},
-	"iterator": {
-		"Create": "Neo.Iterator.Create",
-		"Key":    "Neo.Iterator.Key",
-		"Keys":   "Neo.Iterator.Keys",
-		"Values": "Neo.Iterator.Values",
-	},
-}
+// Interop functions are bound to a syscall of the NEO VM with a directive
+// in their doc comment, for example:
+//
+//	// Put value at given key
+//	//
+//	//neo:syscall Neo.Storage.Put
+//	func Put(ctx Context, key interface{}, value interface{}) {}
+//
+// Calls to these functions are compiled to the syscall instead of a call to
+// the function body, no matter which package declares them or under which
+// name that package is imported.
+const syscallDirectivePrefix = "//neo:syscall "
+
+// Functions with an opcode directive are compiled to the given opcodes,
+// which are emitted after the arguments and the syscall if there is any.
+// Like with any other call the first argument is on top of the stack.
+//
+//	//neo:syscall Neo.Storage.Get
+//	//neo:opcode PUSH0 ADD
+//	func GetInt(ctx Context, key interface{}) int { return 0 }
+const opcodeDirectivePrefix = "//neo:opcode "
+
+// Functions with an event directive declare an event with the given name.
+// Calls to them are compiled to runtime.Notify with the name of the event
+// followed by the arguments. Their body needs to be empty.
+//
+//	// Transfer is emitted when tokens are transferred.
+//	//
+//	//neo:event transfer
+//	func Transfer(from, to [20]byte, amount int) {}
+const eventDirectivePrefix = "//neo:event "
+
+// Functions with an appcall directive in their doc comment are compiled to
+// an APPCALL of the contract with the script hash given as first argument.
+const appcallDirective = "//neo:appcall"
@@ -0,0 +1,207 @@
+package compiler
+
+import (
+	"go/ast"
+	"go/parser"
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"golang.org/x/tools/go/loader"
+)
+
+const interopPath = "../interop"
+
+// Exported functions of the interop packages that are not backed by a syscall.
+var nonSyscalls = map[string]bool{
+	// These are inlined as constants.
+	"runtime.Application":   true,
+	"runtime.ApplicationR":  true,
+	"runtime.Verification":  true,
+	"runtime.VerificationR": true,
+	// Compiled to an APPCALL.
+	"contract.Call": true,
+}
+
+// Files of the interop packages with library code that is written in Go
+// and compiled into the contract like any other code.
+var libraryFiles = map[string]bool{
+	"attachment/attachment.go": true,
+	"storage/collections.go":   true,
+}
+
+// The interop APIs of NEO 2.x, as registered by its StateReader and
+// StateMachine.
+var neoAPIs = apiSet(
+	"System.ExecutionEngine.GetScriptContainer",
+	"System.ExecutionEngine.GetExecutingScriptHash",
+	"System.ExecutionEngine.GetCallingScriptHash",
+	"System.ExecutionEngine.GetEntryScriptHash",
+	"System.Storage.PutEx",
+
+	"Neo.Runtime.GetTrigger",
+	"Neo.Runtime.CheckWitness",
+	"Neo.Runtime.Notify",
+	"Neo.Runtime.Log",
+	"Neo.Runtime.GetTime",
+	"Neo.Runtime.Serialize",
+	"Neo.Runtime.Deserialize",
+
+	"Neo.Blockchain.GetHeight",
+	"Neo.Blockchain.GetHeader",
+	"Neo.Blockchain.GetBlock",
+	"Neo.Blockchain.GetTransaction",
+	"Neo.Blockchain.GetTransactionHeight",
+	"Neo.Blockchain.GetAccount",
+	"Neo.Blockchain.GetValidators",
+	"Neo.Blockchain.GetAsset",
+	"Neo.Blockchain.GetContract",
+
+	"Neo.Header.GetHash",
+	"Neo.Header.GetVersion",
+	"Neo.Header.GetPrevHash",
+	"Neo.Header.GetMerkleRoot",
+	"Neo.Header.GetTimestamp",
+	"Neo.Header.GetIndex",
+	"Neo.Header.GetConsensusData",
+	"Neo.Header.GetNextConsensus",
+
+	"Neo.Block.GetTransactionCount",
+	"Neo.Block.GetTransactions",
+	"Neo.Block.GetTransaction",
+
+	"Neo.Transaction.GetHash",
+	"Neo.Transaction.GetType",
+	"Neo.Transaction.GetAttributes",
+	"Neo.Transaction.GetInputs",
+	"Neo.Transaction.GetOutputs",
+	"Neo.Transaction.GetReferences",
+	"Neo.Transaction.GetUnspentCoins",
+	"Neo.Transaction.GetWitnesses",
+	"Neo.InvocationTransaction.GetScript",
+	"Neo.Witness.GetVerificationScript",
+	"Neo.Attribute.GetUsage",
+	"Neo.Attribute.GetData",
+	"Neo.Input.GetHash",
+	"Neo.Input.GetIndex",
+	"Neo.Output.GetAssetId",
+	"Neo.Output.GetValue",
+	"Neo.Output.GetScriptHash",
+
+	"Neo.Account.GetScriptHash",
+	"Neo.Account.GetVotes",
+	"Neo.Account.GetBalance",
+	"Neo.Account.IsStandard",
+
+	"Neo.Asset.Create",
+	"Neo.Asset.Renew",
+	"Neo.Asset.GetAssetId",
+	"Neo.Asset.GetAssetType",
+	"Neo.Asset.GetAmount",
+	"Neo.Asset.GetAvailable",
+	"Neo.Asset.GetPrecision",
+	"Neo.Asset.GetOwner",
+	"Neo.Asset.GetAdmin",
+	"Neo.Asset.GetIssuer",
+
+	"Neo.Contract.Create",
+	"Neo.Contract.Migrate",
+	"Neo.Contract.Destroy",
+	"Neo.Contract.GetScript",
+	"Neo.Contract.IsPayable",
+	"Neo.Contract.GetStorageContext",
+
+	"Neo.Storage.GetContext",
+	"Neo.Storage.GetReadOnlyContext",
+	"Neo.Storage.Get",
+	"Neo.Storage.Put",
+	"Neo.Storage.Delete",
+	"Neo.Storage.Find",
+	"Neo.StorageContext.AsReadOnly",
+
+	"Neo.Iterator.Create",
+	"Neo.Iterator.Next",
+	"Neo.Iterator.Key",
+	"Neo.Iterator.Value",
+	"Neo.Iterator.Keys",
+	"Neo.Iterator.Values",
+	"Neo.Enumerator.Create",
+	"Neo.Enumerator.Next",
+	"Neo.Enumerator.Value",
+	"Neo.Enumerator.Concat",
+)
+
+func apiSet(apis ...string) map[string]bool {
+	set := make(map[string]bool, len(apis))
+	for _, api := range apis {
+		set[api] = true
+	}
+	return set
+}
+
+// loadInterop loads the interop packages.
+func loadInterop(t *testing.T) *loader.Program {
+	infos, err := ioutil.ReadDir(interopPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := loader.Config{ParserMode: parser.ParseComments}
+	for _, info := range infos {
+		if info.IsDir() {
+			conf.Import(path.Join("github.com/CityOfZion/neo-storm/interop", info.Name()))
+		}
+	}
+	prog, err := conf.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prog
+}
+
+func TestSyscallsComplete(t *testing.T) {
+	prog := loadInterop(t)
+	for _, pkg := range prog.Imported {
+		for _, f := range pkg.Files {
+			filename := prog.Fset.Position(f.Pos()).Filename
+			if libraryFiles[path.Join(pkg.Pkg.Name(), path.Base(filename))] {
+				continue
+			}
+			for _, decl := range f.Decls {
+				fun, ok := decl.(*ast.FuncDecl)
+				if !ok || !fun.Name.IsExported() {
+					continue
+				}
+				qualified := pkg.Pkg.Name() + "." + fun.Name.Name
+				// Functions that are compiled to opcodes or by the compiler itself
+				// need no syscall.
+				if nonSyscalls[qualified] || isBuiltin(fun.Name) || len(opcodeDirective(fun)) > 0 ||
+					interopBuiltins[pkg.Pkg.Path()+"."+fun.Name.Name] {
+					continue
+				}
+				if directiveArg(fun, syscallDirectivePrefix) == "" {
+					t.Errorf("interop function %s has no syscall directive", qualified)
+				}
+			}
+		}
+	}
+}
+
+// Every syscall directive names an interop API of NEO 2.x, so a typo does not
+// compile to a SYSCALL that FAULTs on the network.
+func TestSyscallsKnown(t *testing.T) {
+	prog := loadInterop(t)
+	for _, pkg := range prog.Imported {
+		for _, f := range pkg.Files {
+			for _, decl := range f.Decls {
+				fun, ok := decl.(*ast.FuncDecl)
+				if !ok {
+					continue
+				}
+				if api := directiveArg(fun, syscallDirectivePrefix); api != "" && !neoAPIs[api] {
+					t.Errorf("%s.%s calls %s, which is not an interop API of NEO 2.x", pkg.Pkg.Name(), fun.Name.Name, api)
+				}
+			}
+		}
+	}
+}
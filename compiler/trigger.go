@@ -0,0 +1,165 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+
+	"github.com/CityOfZion/neo-storm/vm"
+)
+
+// The syscall that returns the trigger the contract is invoked with.
+const triggerSyscall = "Neo.Runtime.GetTrigger"
+
+// triggerValue returns the value of the trigger with the given name, see
+// Options.Trigger.
+func triggerValue(name string) (constant.Value, error) {
+	val, ok := vm.Triggers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown trigger %s, expected verification, verificationR, application or applicationR", name)
+	}
+	return constant.MakeInt64(int64(val)), nil
+}
+
+// isTriggerCall returns true if expr is a call to runtime.GetTrigger and the
+// program is compiled for a single trigger.
+func (c *codegen) isTriggerCall(expr ast.Expr) bool {
+	if c.buildInfo.trigger == nil {
+		return false
+	}
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	f := c.calledFunc(call)
+	return f != nil && f.syscall == triggerSyscall
+}
+
+// calledFunc returns the scope of the function called by the given call
+// expression, or nil if it is not a function declared in the program.
+func (c *codegen) calledFunc(call *ast.CallExpr) *funcScope {
+	var ident *ast.Ident
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	default:
+		return nil
+	}
+	return c.funcs[c.funcNameOfIdent(ident)]
+}
+
+// analyzeTriggerVars returns the variables of the function that only get
+// assigned the result of runtime.GetTrigger, like trigger in:
+// trigger := runtime.GetTrigger()
+// When the program is compiled for a single trigger these are constant.
+func (c *codegen) analyzeTriggerVars(decl *ast.FuncDecl) map[types.Object]bool {
+	vars := map[types.Object]bool{}
+	if c.buildInfo.trigger == nil {
+		return vars
+	}
+	assign := func(lhs ast.Expr, rhs ast.Expr) {
+		ident, ok := lhs.(*ast.Ident)
+		if !ok {
+			return
+		}
+		obj := c.typeInfo.ObjectOf(ident)
+		prev, seen := vars[obj]
+		vars[obj] = (!seen || prev) && rhs != nil && c.isTriggerCall(rhs)
+	}
+	ast.Inspect(decl.Body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.AssignStmt:
+			for i, lhs := range n.Lhs {
+				var rhs ast.Expr
+				if (n.Tok == token.ASSIGN || n.Tok == token.DEFINE) && len(n.Lhs) == len(n.Rhs) {
+					rhs = n.Rhs[i]
+				}
+				assign(lhs, rhs)
+			}
+		case *ast.ValueSpec:
+			for i, name := range n.Names {
+				var rhs ast.Expr
+				if len(n.Values) == len(n.Names) {
+					rhs = n.Values[i]
+				}
+				assign(name, rhs)
+			}
+		case *ast.IncDecStmt:
+			assign(n.X, nil)
+		case *ast.UnaryExpr:
+			if n.Op == token.AND {
+				assign(n.X, nil)
+			}
+		}
+		return true
+	})
+	return vars
+}
+
+// constValue returns the value of expr if it is known at compile time. Next
+// to constants these are calls to functions that return a constant, like
+// runtime.Application(), and the trigger when compiling for a single trigger.
+func (c *codegen) constValue(expr ast.Expr) (constant.Value, bool) {
+	if t := c.typeInfo.Types[expr]; t.Value != nil {
+		return t.Value, true
+	}
+	switch n := expr.(type) {
+	case *ast.ParenExpr:
+		return c.constValue(n.X)
+	case *ast.Ident:
+		if c.triggerVars[c.typeInfo.ObjectOf(n)] {
+			return c.buildInfo.trigger, true
+		}
+	case *ast.CallExpr:
+		if c.isTriggerCall(n) {
+			return c.buildInfo.trigger, true
+		}
+		if f := c.calledFunc(n); f != nil && f.value.Value != nil {
+			return f.value.Value, true
+		}
+	}
+	return nil, false
+}
+
+// constCond returns the value of the given condition if it is known at
+// compile time.
+// if trigger == runtime.Verification() {}
+func (c *codegen) constCond(expr ast.Expr) (bool, bool) {
+	switch n := expr.(type) {
+	case *ast.ParenExpr:
+		return c.constCond(n.X)
+	case *ast.UnaryExpr:
+		if n.Op == token.NOT {
+			val, ok := c.constCond(n.X)
+			return !val, ok
+		}
+	case *ast.BinaryExpr:
+		switch n.Op {
+		case token.LAND, token.LOR:
+			// The right side can only be left out if it is not evaluated
+			// at runtime either.
+			x, ok := c.constCond(n.X)
+			if !ok {
+				return false, false
+			}
+			if n.Op == token.LAND && !x || n.Op == token.LOR && x {
+				return x, true
+			}
+			return c.constCond(n.Y)
+		case token.EQL, token.NEQ:
+			x, xok := c.constValue(n.X)
+			y, yok := c.constValue(n.Y)
+			if xok && yok && x.Kind() == y.Kind() {
+				return constant.Compare(x, n.Op, y), true
+			}
+		}
+	}
+	if val, ok := c.constValue(expr); ok && val.Kind() == constant.Bool {
+		return constant.BoolVal(val), true
+	}
+	return false, false
+}
@@ -0,0 +1,150 @@
+package compiler_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/CityOfZion/neo-storm/vm"
+)
+
+func TestTypeAssertErrors(t *testing.T) {
+	var cases = []struct {
+		name   string
+		src    string
+		expect string
+	}{
+		{
+			"indistinguishable cases",
+			`switch x.(type) {
+			case int:
+				return 1
+			case string:
+				return 2
+			}
+			return 0`,
+			"is indistinguishable from case int",
+		},
+		{
+			"struct",
+			`if _, ok := x.(point); ok {
+				return 1
+			}
+			return 0`,
+			"can not be checked at runtime",
+		},
+		{
+			"slice",
+			`switch x.(type) {
+			case []int:
+				return 1
+			}
+			return 0`,
+			"can not be checked at runtime",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			src := `package foo
+			type point struct {
+				x, y int
+			}
+			func Main(x interface{}) int {
+				` + c.src + `
+			}`
+			if out := compileError(t, src); !strings.Contains(out, c.expect) {
+				t.Fatalf("expected %q, got:\n%s", c.expect, out)
+			}
+		})
+	}
+}
+
+func TestTypeSwitch(t *testing.T) {
+	src := `package foo
+	func Main(op string, x interface{}) int {
+		if op == "int" {
+			switch v := x.(type) {
+			case [20]byte:
+				return 20
+			case int:
+				return v + 1
+			}
+		}
+		if op == "string" {
+			switch v := x.(type) {
+			case [20]byte:
+				return 20
+			case string:
+				if v == "neo" {
+					return 3
+				}
+			}
+		}
+		if op == "bytes" {
+			switch v := x.(type) {
+			case [20]byte:
+				return 20
+			case []byte:
+				return len(v)
+			}
+		}
+		return -1
+	}`
+
+	var cases = []struct {
+		op     string
+		x      vm.StackItem
+		expect int64
+	}{
+		{"int", vm.NewIntegerInt64(41), 42},
+		{"string", vm.NewByteArray([]byte("neo")), 3},
+		{"bytes", vm.NewByteArray(make([]byte, 5)), 5},
+		{"int", vm.NewByteArray(make([]byte, 20)), 20},
+		{"string", vm.NewByteArray(make([]byte, 20)), 20},
+		{"bytes", vm.NewByteArray(make([]byte, 20)), 20},
+	}
+	for _, c := range cases {
+		res := runContract(t, src, vm.NewByteArray([]byte(c.op)), c.x)
+		if res.BigInt().Int64() != c.expect {
+			t.Fatalf("%s %s: expected %d, got %s", c.op, c.x, c.expect, res)
+		}
+	}
+}
+
+func TestCommaOkAssert(t *testing.T) {
+	src := `package foo
+	func Main(x interface{}) []byte {
+		h, ok := x.([20]byte)
+		if !ok {
+			return append(h[:], 0xff)
+		}
+		return h[:]
+	}`
+
+	hash := bytes.Repeat([]byte{1}, 20)
+	res := runContract(t, src, vm.NewByteArray(hash)).Bytes()
+	if !bytes.Equal(res, hash) {
+		t.Fatalf("expected %x, got %x", hash, res)
+	}
+	// The value is the zero value of the type if the size is wrong.
+	res = runContract(t, src, vm.NewByteArray(make([]byte, 19))).Bytes()
+	if expect := append(make([]byte, 20), 0xff); !bytes.Equal(res, expect) {
+		t.Fatalf("expected %x, got %x", expect, res)
+	}
+}
+
+func TestTypeCheckInterop(t *testing.T) {
+	src := `package foo
+	func Main(x interface{}) int {
+		if _, ok := x.([20]byte); ok {
+			return 20
+		}
+		return 0
+	}`
+
+	// The size of an interop interface can not be checked, the VM FAULTs.
+	v := loadContract(t, nil, src, vm.NewInteropInterface(struct{}{}))
+	if err := v.Run(); err == nil || !strings.Contains(err.Error(), "ARRAYSIZE") {
+		t.Fatalf("expected the check of an interop interface to FAULT, got %v", err)
+	}
+}
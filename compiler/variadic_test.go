@@ -0,0 +1,108 @@
+package compiler_test
+
+import (
+	"testing"
+
+	"github.com/CityOfZion/neo-storm/vm"
+)
+
+func TestVariadicPack(t *testing.T) {
+	src := `package foo
+	func Main() int {
+		return sum(7, 1, 2, 3)
+	}
+	func sum(first int, rest ...int) int {
+		return first
+	}`
+
+	// The variadic arguments are reversed and packed, then they are
+	// swapped with the first argument.
+	b := compileSource(t, src)
+	if !hasOpcodes(b, vm.PUSH7, vm.PUSH1, vm.PUSH2, vm.PUSH3, vm.PUSH2, vm.XSWAP, vm.PUSH3, vm.PACK, vm.SWAP) {
+		t.Fatalf("expected the variadic arguments to be packed, got %x", b)
+	}
+}
+
+func TestVariadicSpread(t *testing.T) {
+	src := `package foo
+	func Main(xs []int) int {
+		return sum(7, xs...)
+	}
+	func sum(first int, rest ...int) int {
+		return first
+	}`
+
+	b := compileSource(t, src)
+	if hasOpcodes(b, vm.PACK) {
+		t.Fatalf("expected the spread slice to be passed as is, got %x", b)
+	}
+}
+
+func TestVariadicCall(t *testing.T) {
+	src := `package foo
+	type number struct {
+		base int
+	}
+	func Main(op string) int {
+		if op == "none" {
+			return digits(1)
+		}
+		if op == "one" {
+			return digits(1, 2)
+		}
+		if op == "two" {
+			return digits(1, 2, 3)
+		}
+		if op == "several" {
+			return digits(1, 2, 3, 4, 5, 6)
+		}
+		if op == "spread" {
+			xs := []int{2, 3, 4}
+			return digits(1, xs...)
+		}
+		if op == "method" {
+			n := number{base: 9}
+			return n.digits(8, 7, 6)
+		}
+		if op == "fixed" {
+			return fixed(1, 2, 3, 4, 5)
+		}
+		return -1
+	}
+	// digits returns the number with the given digits, so the result shows
+	// the order the arguments were passed in.
+	func digits(first int, rest ...int) int {
+		n := first
+		for i := 0; i < len(rest); i++ {
+			n = n*10 + rest[i]
+		}
+		return n
+	}
+	func (n number) digits(rest ...int) int {
+		return digits(n.base, rest...)
+	}
+	func fixed(a, b, c, d, e int) int {
+		return digits(a, b, c, d, e)
+	}`
+
+	var cases = []struct {
+		op     string
+		expect int64
+	}{
+		{"none", 1},
+		{"one", 12},
+		{"two", 123},
+		{"several", 123456},
+		{"spread", 1234},
+		{"method", 9876},
+		{"fixed", 12345},
+	}
+	for _, c := range cases {
+		t.Run(c.op, func(t *testing.T) {
+			res := runContract(t, src, vm.NewByteArray([]byte(c.op)))
+			if res.BigInt().Int64() != c.expect {
+				t.Fatalf("expected %d, got %s", c.expect, res)
+			}
+		})
+	}
+}
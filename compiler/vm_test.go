@@ -0,0 +1,240 @@
+package compiler_test
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/CityOfZion/neo-go/pkg/util"
+	"github.com/CityOfZion/neo-storm/compiler"
+	"github.com/CityOfZion/neo-storm/vm"
+)
+
+// runContract compiles src and executes it with the given arguments, it
+// returns the item that is left on the evaluation stack.
+func runContract(t *testing.T, src string, args ...vm.StackItem) vm.StackItem {
+	return runContractIn(t, nil, src, args...)
+}
+
+// runContractIn runs the contract like runContract does, against the given
+// environment if it is not nil.
+func runContractIn(t *testing.T, env *vm.Environment, src string, args ...vm.StackItem) vm.StackItem {
+	v := loadContract(t, env, src, args...)
+	if err := v.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if v.Estack().Len() != 1 {
+		t.Fatalf("expected 1 item on the stack, got %d", v.Estack().Len())
+	}
+	return v.Estack().Pop()
+}
+
+// loadContract compiles src and loads it on a new VM with the given
+// arguments, which executes against the environment if it is not nil.
+func loadContract(t *testing.T, env *vm.Environment, src string, args ...vm.StackItem) *vm.VM {
+	b, err := compiler.Compile(strings.NewReader(src), &compiler.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := vm.New()
+	if env != nil {
+		env.Attach(v)
+	}
+	for i := len(args) - 1; i >= 0; i-- {
+		v.Estack().Push(args[i])
+	}
+	v.LoadScript(b)
+	return v
+}
+
+func TestRunContract(t *testing.T) {
+	var cases = []struct {
+		name   string
+		src    string
+		args   []vm.StackItem
+		expect interface{}
+	}{
+		{
+			name: "arithmetic",
+			src: `package foo
+			func Main(a, b int) int {
+				return (a+b)*2 - a/b
+			}`,
+			args:   []vm.StackItem{vm.NewIntegerInt64(7), vm.NewIntegerInt64(2)},
+			expect: big.NewInt(15),
+		},
+		{
+			name: "large and negative constants",
+			src: `package foo
+			func Main() int {
+				x := 10000000
+				y := -129
+				return x + y - -x
+			}`,
+			expect: big.NewInt(19999871),
+		},
+		{
+			name: "loop",
+			src: `package foo
+			func Main(n int) int {
+				sum := 0
+				for i := 0; i < n; i++ {
+					if i != 5 {
+						sum += i
+					}
+				}
+				return sum
+			}`,
+			args:   []vm.StackItem{vm.NewIntegerInt64(10)},
+			expect: big.NewInt(40),
+		},
+		{
+			name: "function call",
+			src: `package foo
+			func Main() int {
+				return fib(10)
+			}
+			func fib(n int) int {
+				if n < 2 {
+					return n
+				}
+				return fib(n-1) + fib(n-2)
+			}`,
+			expect: big.NewInt(55),
+		},
+		{
+			name: "struct",
+			src: `package foo
+			type token struct {
+				name   string
+				supply int
+			}
+			func Main() int {
+				t := token{name: "foo", supply: 10}
+				t.supply = t.supply + 5
+				return t.supply
+			}`,
+			expect: big.NewInt(15),
+		},
+		{
+			name: "append",
+			src: `package foo
+			func Main() int {
+				x := []int{1, 2}
+				x = append(x, 3, 4)
+				return len(x) + x[3]
+			}`,
+			expect: big.NewInt(8),
+		},
+		{
+			name: "strings",
+			src: `package foo
+			func Main(s string) string {
+				return s + " world"
+			}`,
+			args:   []vm.StackItem{vm.NewByteArray([]byte("hello"))},
+			expect: []byte("hello world"),
+		},
+		{
+			name: "bool",
+			src: `package foo
+			func Main(a int) bool {
+				return a > 3 && a != 5
+			}`,
+			args:   []vm.StackItem{vm.NewIntegerInt64(4)},
+			expect: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			res := runContract(t, c.src, c.args...).Value()
+			if !reflect.DeepEqual(res, c.expect) {
+				t.Fatalf("expected %v, got %v", c.expect, res)
+			}
+		})
+	}
+}
+
+func TestRunContractEnvironment(t *testing.T) {
+	src := `package foo
+	import (
+		"github.com/CityOfZion/neo-storm/interop/iterator"
+		"github.com/CityOfZion/neo-storm/interop/runtime"
+		"github.com/CityOfZion/neo-storm/interop/storage"
+	)
+	type order struct {
+		id     int
+		amount int
+	}
+	func Main(owner []byte) int {
+		if !runtime.CheckWitness(owner) {
+			return -1
+		}
+		ctx := storage.GetContext()
+		orders := storage.NewList(ctx, "orders")
+		orders.Append(10)
+		orders.Append(20)
+		storage.PutStruct(ctx, "last", order{id: 2, amount: 20})
+
+		var o order
+		storage.GetStruct(ctx, "last", &o)
+		runtime.Notify(o.amount)
+
+		sum := 0
+		it := storage.Find(ctx, "orders#")
+		for iterator.Next(it) {
+			sum = sum + storage.GetInt(ctx, iterator.Key(it))
+		}
+		return sum + orders.Len()
+	}`
+
+	owner := []byte("01234567890123456789")
+	env := vm.NewEnvironment()
+	res := runContractIn(t, env, src, vm.NewByteArray(owner))
+	if res.BigInt().Int64() != -1 {
+		t.Fatalf("expected the witness check to fail, got %s", res)
+	}
+
+	hash, _ := util.Uint160DecodeBytes(owner)
+	env.Witnesses = append(env.Witnesses, hash)
+	res = runContractIn(t, env, src, vm.NewByteArray(owner))
+	if res.BigInt().Int64() != 32 {
+		t.Fatalf("expected 32, got %s", res)
+	}
+	// The arguments of Notify are packed in an array.
+	if len(env.Notifications) != 1 {
+		t.Fatalf("expected 1 notification, got %v", env.Notifications)
+	}
+	if args := env.Notifications[0].Item.Value().([]vm.StackItem); args[0].BigInt().Int64() != 20 {
+		t.Fatalf("expected 20 to be notified, got %s", args[0])
+	}
+}
+
+// The results of syscalls that are called as a statement are dropped.
+func TestSyscallResultDropped(t *testing.T) {
+	src := `package foo
+	import "github.com/CityOfZion/neo-storm/interop/asset"
+	func Main(a asset.Asset) int {
+		asset.Renew(a, 1)
+		return 7
+	}`
+	v := loadContract(t, nil, src, vm.NewByteArray([]byte("asset")))
+	v.InteropService().Register("Neo.Asset.Renew", func(v *vm.VM) error {
+		v.Estack().Pop()
+		v.Estack().Pop()
+		v.Estack().Push(vm.NewIntegerInt64(2000000))
+		return nil
+	})
+	if err := v.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if v.Estack().Len() != 1 {
+		t.Fatalf("expected 1 item on the stack, got %d", v.Estack().Len())
+	}
+	if res := v.Estack().Pop().BigInt().Int64(); res != 7 {
+		t.Fatalf("expected 7, got %d", res)
+	}
+}
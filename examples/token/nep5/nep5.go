@@ -23,18 +23,23 @@ type Token struct {
 	CirculationKey string
 }
 
+// OnTransfer is emitted when tokens are transferred.
+//
+//neo:event transfer
+func OnTransfer(from, to [20]byte, amount int) {}
+
 // GetSupply gets the token totalSupply value from VM storage
 func (t Token) GetSupply(ctx storage.Context) interface{} {
 	return storage.Get(ctx, t.CirculationKey)
 }
 
 // BalanceOf gets the token balance of a specific address
-func (t Token) BalanceOf(ctx storage.Context, hodler []byte) interface{} {
+func (t Token) BalanceOf(ctx storage.Context, hodler [20]byte) interface{} {
 	return storage.Get(ctx, hodler)
 }
 
 // Transfer token from one user to another
-func (t Token) Transfer(ctx storage.Context, from []byte, to []byte, amount int) bool {
+func (t Token) Transfer(ctx storage.Context, from, to [20]byte, amount int) bool {
 	amountFrom := t.CanTransfer(ctx, from, to, amount)
 	if amountFrom == -1 {
 		return false
@@ -52,13 +57,13 @@ func (t Token) Transfer(ctx storage.Context, from []byte, to []byte, amount int)
 	amountTo := storage.Get(ctx, to).(int)
 	totalAmountTo := amountTo + amount
 	storage.Put(ctx, to, totalAmountTo)
-	runtime.Notify("transfer", from, to, amount)
+	OnTransfer(from, to, amount)
 	return true
 }
 
 // CanTransfer returns the amount it can transfer
-func (t Token) CanTransfer(ctx storage.Context, from []byte, to []byte, amount int) int {
-	if len(to) != 20 && !IsUsableAddress(from) {
+func (t Token) CanTransfer(ctx storage.Context, from, to [20]byte, amount int) int {
+	if !IsUsableAddress(from) {
 		return -1
 	}
 
@@ -77,18 +82,15 @@ func (t Token) CanTransfer(ctx storage.Context, from []byte, to []byte, amount i
 }
 
 // IsUsableAddress checks if the sender is either the correct NEO address or SC address
-func IsUsableAddress(addr []byte) bool {
-	if len(addr) == 20 {
-
-		if runtime.CheckWitness(addr) {
-			return true
-		}
-
-		// Check if a smart contract is calling scripthash
-		callingScriptHash := engine.GetCallingScriptHash()
-		if util.Equals(callingScriptHash, addr) {
-			return true
-		}
+func IsUsableAddress(addr [20]byte) bool {
+	if runtime.CheckWitness(addr[:]) {
+		return true
+	}
+
+	// Check if a smart contract is calling scripthash
+	callingScriptHash := engine.GetCallingScriptHash()
+	if util.Equals(callingScriptHash, addr) {
+		return true
 	}
 
 	return false
@@ -47,12 +47,12 @@ func Main(operation string, args []interface{}) interface{} {
 		return token.GetSupply(ctx)
 	}
 	if operation == "balanceOf" {
-		hodler := args[0].([]byte)
+		hodler := args[0].([20]byte)
 		return token.BalanceOf(ctx, hodler)
 	}
 	if operation == "transfer" && CheckArgs(args, 3) {
-		from := args[0].([]byte)
-		to := args[1].([]byte)
+		from := args[0].([20]byte)
+		to := args[1].([20]byte)
 		amount := args[2].(int)
 		return token.Transfer(ctx, from, to, amount)
 	}
@@ -7,17 +7,23 @@ package account
 type Account struct{}
 
 // GetScripHash returns the script hash of the given account.
+//
+//neo:syscall Neo.Account.GetScriptHash
 func GetScriptHash(a Account) []byte {
 	return nil
 }
 
 // GetVotes returns the votes of the given account which should be a slice of
 // public key raw bytes.
+//
+//neo:syscall Neo.Account.GetVotes
 func GetVotes(a Account) [][]byte {
 	return nil
 }
 
 // GetBalance returns the balance of for the given account and asset id.
+//
+//neo:syscall Neo.Account.GetBalance
 func GetBalance(a Account, assetID []byte) int {
 	return 0
 }
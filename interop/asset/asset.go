@@ -7,47 +7,72 @@ package asset
 type Asset struct{}
 
 // GetAssetID returns the id of the given asset.
+//
+//neo:syscall Neo.Asset.GetAssetId
 func GetAssetID(a Asset) []byte {
 	return nil
 }
 
 // GetAssetType returns the type of the given asset.
+//
+//neo:syscall Neo.Asset.GetAssetType
 func GetAssetType(a Asset) byte {
 	return 0x00
 }
 
 // GetAmount returns the amount of the given asset.
+//
+//neo:syscall Neo.Asset.GetAmount
 func GetAmount(a Asset) int {
 	return 0
 }
 
 // GetAvailable returns the available of the given asset.
+//
+//neo:syscall Neo.Asset.GetAvailable
 func GetAvailable(a Asset) int {
 	return 0
 }
 
 // GetPrecision returns the precision of the given asset.
+//
+//neo:syscall Neo.Asset.GetPrecision
 func GetPrecision(a Asset) byte {
 	return 0x00
 }
 
 // GetOwner returns the owner of the given asset.
+//
+//neo:syscall Neo.Asset.GetOwner
 func GetOwner(a Asset) []byte {
 	return nil
 }
 
 // GetAdmin returns the admin of the given asset.
+//
+//neo:syscall Neo.Asset.GetAdmin
 func GetAdmin(a Asset) []byte {
 	return nil
 }
 
 // GetIssuer returns the issuer of the given asset.
+//
+//neo:syscall Neo.Asset.GetIssuer
 func GetIssuer(a Asset) []byte {
 	return nil
 }
 
-// Create registers a new asset on the blockchain.
-func Create(assetType byte, name string, amount int, precision byte, owner, admin, issuer []byte) {}
+// Create registers a new asset on the blockchain and returns it.
+//
+//neo:syscall Neo.Asset.Create
+func Create(assetType byte, name string, amount int, precision byte, owner, admin, issuer []byte) Asset {
+	return Asset{}
+}
 
-// Renew renews the existance of an asset by the given years.
-func Renew(asset Asset, years int) {}
+// Renew renews the existance of an asset by the given years and returns the
+// block height at which it expires.
+//
+//neo:syscall Neo.Asset.Renew
+func Renew(asset Asset, years int) int {
+	return 0
+}
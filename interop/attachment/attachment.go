@@ -0,0 +1,75 @@
+package attachment
+
+import (
+	"github.com/CityOfZion/neo-storm/interop/engine"
+	"github.com/CityOfZion/neo-storm/interop/output"
+	"github.com/CityOfZion/neo-storm/interop/transaction"
+	"github.com/CityOfZion/neo-storm/interop/util"
+)
+
+// Package attachment provides helpers to inspect the assets that are attached
+// to the transaction that invokes the contract. Unlike most of the interop
+// packages these are written in Go and compiled into the contract.
+
+// Asset IDs of the global assets, in the byte order of output.GetAssetID.
+const (
+	// NEO is the asset ID of NEO.
+	NEO = "\x9b\x7c\xff\xda\xa6\x74\xbe\xae\x0f\x93\x0e\xbe\x60\x85\xaf\x90\x93\xe5\xfe\x56\xb3\x4a\x5c\x22\x0c\xcd\xcf\x6e\xfc\x33\x6f\xc5"
+	// GAS is the asset ID of GAS.
+	GAS = "\xe7\x2d\x28\x69\x79\xee\x6c\xb1\xb7\xe6\x5d\xfd\xdf\xb2\xe3\x84\x10\x0b\x8d\x14\x8e\x77\x58\xde\x42\xe4\x16\x8b\x71\x79\x2c\x60"
+)
+
+// Received returns the amount of the given asset the transaction sends to the
+// executing contract. Like all amounts of the outputs it is multiplied by
+// 10^8.
+func Received(assetID []byte) int {
+	tx := engine.GetScriptContainer()
+	outputs := transaction.GetOutputs(tx)
+	self := engine.GetExecutingScriptHash()
+
+	total := 0
+	for i := 0; i < len(outputs); i++ {
+		out := outputs[i]
+		if util.Equals(output.GetScriptHash(out), self) && util.Equals(output.GetAssetID(out), assetID) {
+			total += output.GetValue(out)
+		}
+	}
+	return total
+}
+
+// ReceivedNEO returns the amount of NEO the transaction sends to the
+// executing contract.
+func ReceivedNEO() int {
+	return Received([]byte(NEO))
+}
+
+// ReceivedGAS returns the amount of GAS the transaction sends to the
+// executing contract.
+func ReceivedGAS() int {
+	return Received([]byte(GAS))
+}
+
+// Senders returns the script hashes of the outputs the transaction spends,
+// which are the addresses that sent the attached assets. A script hash is
+// returned for every spent output, so it can occur more than once.
+func Senders() [][]byte {
+	tx := engine.GetScriptContainer()
+	references := transaction.GetReferences(tx)
+
+	senders := [][]byte{}
+	for i := 0; i < len(references); i++ {
+		senders = append(senders, output.GetScriptHash(references[i]))
+	}
+	return senders
+}
+
+// Sender returns the script hash of the first output the transaction spends,
+// or an empty byte slice if it does not spend any.
+func Sender() []byte {
+	tx := engine.GetScriptContainer()
+	references := transaction.GetReferences(tx)
+	if len(references) == 0 {
+		return []byte{}
+	}
+	return output.GetScriptHash(references[0])
+}
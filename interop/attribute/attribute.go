@@ -7,11 +7,15 @@ package attribute
 type Attribute struct{}
 
 // GetUsage returns the usage of the given attribute.
+//
+//neo:syscall Neo.Attribute.GetUsage
 func GetUsage(attr Attribute) byte {
 	return 0x00
 }
 
 // GetData returns the data of the given attribute.
+//
+//neo:syscall Neo.Attribute.GetData
 func GetData(attr Attribute) []byte {
 	return nil
 }
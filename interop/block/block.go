@@ -9,17 +9,23 @@ import "github.com/CityOfZion/neo-storm/interop/transaction"
 type Block struct{}
 
 // GetTransactionCount return the number of recorded transactions in the given block.
+//
+//neo:syscall Neo.Block.GetTransactionCount
 func GetTransactionCount(b Block) int {
 	return 0
 }
 
 // GetTransactions returns a slice of transactions recorded in the given block.
+//
+//neo:syscall Neo.Block.GetTransactions
 func GetTransactions(b Block) []transaction.Transaction {
 	return []transaction.Transaction{}
 }
 
 // GetTransaction returns a transaction from the given a block hash of the
 // transaction.
+//
+//neo:syscall Neo.Block.GetTransaction
 func GetTransaction(b Block, hash []byte) transaction.Transaction {
 	return transaction.Transaction{}
 }
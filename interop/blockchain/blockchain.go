@@ -13,41 +13,57 @@ import (
 // smart contracts that are written in the neo-storm framework.
 
 // GetHeight returns the height of te block recorded in the current execution scope.
+//
+//neo:syscall Neo.Blockchain.GetHeight
 func GetHeight() int {
 	return 0
 }
 
 // GetHeader returns the header found by the given hash or index.
+//
+//neo:syscall Neo.Blockchain.GetHeader
 func GetHeader(heightOrHash interface{}) header.Header {
 	return header.Header{}
 }
 
 // GetBlock returns the block found by the given hash or index.
+//
+//neo:syscall Neo.Blockchain.GetBlock
 func GetBlock(heightOrHash interface{}) block.Block {
 	return block.Block{}
 }
 
 // GetTransaction returns the transaction found by the given hash.
+//
+//neo:syscall Neo.Blockchain.GetTransaction
 func GetTransaction(hash []byte) transaction.Transaction {
 	return transaction.Transaction{}
 }
 
 // GetContract returns the contract found by the given script hash.
+//
+//neo:syscall Neo.Blockchain.GetContract
 func GetContract(scriptHash []byte) contract.Contract {
 	return contract.Contract{}
 }
 
 // GetAccount returns the account found by the given script hash.
+//
+//neo:syscall Neo.Blockchain.GetAccount
 func GetAccount(scriptHash []byte) account.Account {
 	return account.Account{}
 }
 
 // GetValidators returns a slice of validator addresses.
+//
+//neo:syscall Neo.Blockchain.GetValidators
 func GetValidators() [][]byte {
 	return nil
 }
 
 // GetAsset returns the asset found by the given asset id.
+//
+//neo:syscall Neo.Blockchain.GetAsset
 func GetAsset(assetID []byte) asset.Asset {
 	return asset.Asset{}
 }
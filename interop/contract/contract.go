@@ -9,22 +9,30 @@ import "github.com/CityOfZion/neo-storm/interop/storage"
 type Contract struct{}
 
 // GetScript returns the script of the given contract.
+//
+//neo:syscall Neo.Contract.GetScript
 func GetScript(c Contract) []byte {
 	return nil
 }
 
 // IsPayable returns whether the given contract is payable.
+//
+//neo:syscall Neo.Contract.IsPayable
 func IsPayable(c Contract) bool {
 	return false
 }
 
 // GetStorageContext returns the storage context for the given contract.
+//
+//neo:syscall Neo.Contract.GetStorageContext
 func GetStorageContext(c Contract) storage.Context {
 	return storage.Context{}
 }
 
 // Create creates a new contract.
 // @FIXME What is the type of the returnType here?
+//
+//neo:syscall Neo.Contract.Create
 func Create(
 	script []byte,
 	params []interface{},
@@ -39,6 +47,8 @@ func Create(
 
 // Migrate migrates a new contract.
 // @FIXME What is the type of the returnType here?
+//
+//neo:syscall Neo.Contract.Migrate
 func Migrate(
 	script []byte,
 	params []interface{},
@@ -52,4 +62,17 @@ func Migrate(
 }
 
 // Destroy deletes a contract that is registered on the blockchain.
+//
+//neo:syscall Neo.Contract.Destroy
 func Destroy(c Contract) {}
+
+// Call calls the given method of the contract with the given script hash and
+// returns its result. If the script hash is known at compile time, like
+// [20]byte([]byte("...")) or [20]byte(util.FromAddress("A...")), the call is static.
+// Otherwise the contract is called with dynamic invoke, which needs to be
+// enabled when deploying the contract.
+//
+//neo:appcall
+func Call(scriptHash [20]byte, method string, args ...interface{}) interface{} {
+	return nil
+}
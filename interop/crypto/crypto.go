@@ -22,3 +22,30 @@ func Hash160(b []byte) []byte {
 func Hash256(b []byte) []byte {
 	return nil
 }
+
+// VerifySignature checks that sig is a valid signature of msg for the given
+// public key.
+//
+// The VM expects the public key on top of the signature and the message.
+//
+//neo:opcode SWAP ROT VERIFY
+func VerifySignature(msg []byte, sig []byte, pubkey []byte) bool {
+	return false
+}
+
+// CheckSig checks that sig is a valid signature of the script container
+// (the transaction) for the given public key.
+//
+//neo:opcode CHECKSIG
+func CheckSig(pubkey []byte, sig []byte) bool {
+	return false
+}
+
+// CheckMultiSig checks that sigs are valid signatures of the script container
+// (the transaction) for the given public keys. The signatures must be in the
+// same order as the public keys they belong to.
+//
+//neo:opcode CHECKMULTISIG
+func CheckMultiSig(pubkeys [][]byte, sigs [][]byte) bool {
+	return false
+}
@@ -6,24 +6,32 @@ import "github.com/CityOfZion/neo-storm/interop/transaction"
 // smart contracts that are written in the neo-storm framework.
 
 // GetScriptContainer returns the transaction that is in the execution context.
+//
+//neo:syscall System.ExecutionEngine.GetScriptContainer
 func GetScriptContainer() transaction.Transaction {
 	return transaction.Transaction{}
 }
 
 // GetExecutingScriptHash returns the script hash of the contract that is
 // currently being executed.
+//
+//neo:syscall System.ExecutionEngine.GetExecutingScriptHash
 func GetExecutingScriptHash() []byte {
 	return nil
 }
 
 // GetCallingScriptHash returns the script hash of the contract that started
 // the execution of the current script.
+//
+//neo:syscall System.ExecutionEngine.GetCallingScriptHash
 func GetCallingScriptHash() []byte {
 	return nil
 }
 
 // GetEntryScriptHash returns the script hash of the contract that started the
 // execution from the start.
+//
+//neo:syscall System.ExecutionEngine.GetEntryScriptHash
 func GetEntryScriptHash() []byte {
 	return nil
 }
@@ -9,21 +9,30 @@ package enumerator
 type Enumerator struct{}
 
 // Create creates a new enumerator from the given items.
+//
+//neo:syscall Neo.Enumerator.Create
 func Create(items []interface{}) Enumerator {
 	return Enumerator{}
 }
 
-// Next returns the next item in the iteration.
-func Next(e Enumerator) interface{} {
-	return nil
+// Next advances the enumerator, return true if it was successful
+// and false otherwise.
+//
+//neo:syscall Neo.Enumerator.Next
+func Next(e Enumerator) bool {
+	return true
 }
 
 // Value returns the enumerator value.
+//
+//neo:syscall Neo.Enumerator.Value
 func Value(e Enumerator) interface{} {
 	return nil
 }
 
 // Concat concats the 2 given enumerators.
+//
+//neo:syscall Neo.Enumerator.Concat
 func Concat(a, b Enumerator) Enumerator {
 	return Enumerator{}
 }
@@ -7,41 +7,57 @@ package header
 type Header struct{}
 
 // GetIndex returns the index of the given header.
+//
+//neo:syscall Neo.Header.GetIndex
 func GetIndex(h Header) int {
 	return 0
 }
 
 // GetHash returns the hash of the given header.
+//
+//neo:syscall Neo.Header.GetHash
 func GetHash(h Header) []byte {
 	return nil
 }
 
 // GetPrevHash returns the previous hash of the given header.
+//
+//neo:syscall Neo.Header.GetPrevHash
 func GetPrevHash(h Header) []byte {
 	return nil
 }
 
 // GetTimestamp returns the timestamp of the given header.
+//
+//neo:syscall Neo.Header.GetTimestamp
 func GetTimestamp(h Header) int {
 	return 0
 }
 
 // GetVersion returns the version of the given header.
+//
+//neo:syscall Neo.Header.GetVersion
 func GetVersion(h Header) int {
 	return 0
 }
 
 // GetMerkleRoot returns the merkle root of the given header.
+//
+//neo:syscall Neo.Header.GetMerkleRoot
 func GetMerkleRoot(h Header) []byte {
 	return nil
 }
 
 // GetConsensusData returns the consensus data of the given header.
+//
+//neo:syscall Neo.Header.GetConsensusData
 func GetConsensusData(h Header) int {
 	return 0
 }
 
 // GetNextConsensus returns the next consensus of the given header.
+//
+//neo:syscall Neo.Header.GetNextConsensus
 func GetNextConsensus(h Header) []byte {
 	return nil
 }
@@ -7,11 +7,15 @@ package input
 type Input struct{}
 
 // GetHash returns the hash of the given input.
+//
+//neo:syscall Neo.Input.GetHash
 func GetHash(in Input) []byte {
 	return nil
 }
 
 // GetIndex returns the index of the given input.
+//
+//neo:syscall Neo.Input.GetIndex
 func GetIndex(in Input) int {
 	return 0
 }
@@ -7,22 +7,45 @@ package iterator
 type Iterator struct{}
 
 // Create creates an iterator from the given items.
+//
+//neo:syscall Neo.Iterator.Create
 func Create(items []interface{}) Iterator {
 	return Iterator{}
 }
 
 // TODO: Better description for this.
 // Key returns the iterator key.
+//
+//neo:syscall Neo.Iterator.Key
 func Key(it Iterator) interface{} {
 	return nil
 }
 
 // Keys returns the iterator keys.
+//
+//neo:syscall Neo.Iterator.Keys
 func Keys(it Iterator) []interface{} {
 	return nil
 }
 
 // Values returns the iterator values.
+//
+//neo:syscall Neo.Iterator.Values
 func Values(it Iterator) []interface{} {
 	return nil
 }
+
+// Next advances the iterator, return true if it was successful
+// and false otherwise.
+//
+//neo:syscall Neo.Iterator.Next
+func Next(it Iterator) bool {
+	return true
+}
+
+// Value returns the iterator value.
+//
+//neo:syscall Neo.Iterator.Value
+func Value(it Iterator) interface{} {
+	return nil
+}
@@ -7,16 +7,22 @@ package output
 type Output struct{}
 
 // GetAssetID returns the asset id of the given output.
+//
+//neo:syscall Neo.Output.GetAssetId
 func GetAssetID(out Output) []byte {
 	return nil
 }
 
 // GetValue returns the value of the given output.
+//
+//neo:syscall Neo.Output.GetValue
 func GetValue(out Output) int {
 	return 0
 }
 
 // GetScriptHash returns the script hash of the given output.
+//
+//neo:syscall Neo.Output.GetScriptHash
 func GetScriptHash(out Output) []byte {
 	return nil
 }
@@ -4,25 +4,33 @@ package runtime
 // smart contracts that are written in the neo-storm framework.
 
 // CheckWitness verifies if the given hash is the invoker of the contract.
+//
+//neo:syscall Neo.Runtime.CheckWitness
 func CheckWitness(hash []byte) bool {
 	return true
 }
 
 // Log instucts the VM to log the given message.
+//
+//neo:syscall Neo.Runtime.Log
 func Log(message string) {}
 
 // Notify an event to the VM.
-func Notify(arg ...interface{}) int {
-	return 0
-}
+//
+//neo:syscall Neo.Runtime.Notify
+func Notify(arg ...interface{}) {}
 
 // GetTime returns the timestamp of the most recent block.
+//
+//neo:syscall Neo.Runtime.GetTime
 func GetTime() int {
 	return 0
 }
 
 // GetTrigger returns the smart contract invoke trigger which can be either
 // verification or application.
+//
+//neo:syscall Neo.Runtime.GetTrigger
 func GetTrigger() byte {
 	return 0x00
 }
@@ -32,17 +40,33 @@ func Application() byte {
 	return 0x10
 }
 
+// ApplicationR returns the ApplicationR trigger type, which is used when the
+// contract receives assets.
+func ApplicationR() byte {
+	return 0x11
+}
+
 // Verification returns the Verification trigger type
 func Verification() byte {
 	return 0x00
 }
 
+// VerificationR returns the VerificationR trigger type, which is used when
+// the contract receives assets.
+func VerificationR() byte {
+	return 0x01
+}
+
 // Serialize serializes and item into a bytearray.
+//
+//neo:syscall Neo.Runtime.Serialize
 func Serialize(item interface{}) []byte {
 	return nil
 }
 
 // Deserialize an item from a bytearray.
+//
+//neo:syscall Neo.Runtime.Deserialize
 func Deserialize(b []byte) interface{} {
 	return nil
 }
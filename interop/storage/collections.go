@@ -0,0 +1,121 @@
+package storage
+
+import "github.com/CityOfZion/neo-storm/interop/iterator"
+
+// The types in this file are written in regular Go and are compiled into the
+// contract like any other code. They namespace their keys with a prefix, so
+// multiple collections can share the storage of a contract. Make sure the
+// prefix of one collection is not the prefix of another one.
+
+// Map is a key value store of which all keys are prefixed.
+type Map struct {
+	Ctx    Context
+	Prefix string
+}
+
+// NewMap returns a Map that stores its keys with the given prefix.
+func NewMap(ctx Context, prefix string) Map {
+	return Map{Ctx: ctx, Prefix: prefix}
+}
+
+// Put puts value at the given key of the map.
+func (m Map) Put(key string, value interface{}) {
+	Put(m.Ctx, m.Prefix+key, value)
+}
+
+// Get returns the value at the given key of the map.
+func (m Map) Get(key string) interface{} {
+	return Get(m.Ctx, m.Prefix+key)
+}
+
+// GetInt returns the value at the given key of the map as an integer,
+// or 0 if the key does not exist.
+func (m Map) GetInt(key string) int {
+	return GetInt(m.Ctx, m.Prefix+key)
+}
+
+// Delete deletes the given key from the map.
+func (m Map) Delete(key string) {
+	Delete(m.Ctx, m.Prefix+key)
+}
+
+// Find returns an iterator over the keys of the map that start with the given
+// key. The keys of the iterator include the prefix of the map.
+func (m Map) Find(key string) iterator.Iterator {
+	return Find(m.Ctx, m.Prefix+key)
+}
+
+// Counter is an integer stored at a single key.
+type Counter struct {
+	Ctx Context
+	Key string
+}
+
+// NewCounter returns a Counter that is stored at the given key.
+func NewCounter(ctx Context, key string) Counter {
+	return Counter{Ctx: ctx, Key: key}
+}
+
+// Get returns the value of the counter, 0 if it was never set.
+func (c Counter) Get() int {
+	return GetInt(c.Ctx, c.Key)
+}
+
+// Add adds n to the counter and returns its new value.
+func (c Counter) Add(n int) int {
+	value := c.Get() + n
+	Put(c.Ctx, c.Key, value)
+	return value
+}
+
+// Next increments the counter and returns its new value.
+func (c Counter) Next() int {
+	return c.Add(1)
+}
+
+// List is a list of values. The length of the list is stored at the prefix,
+// its items are stored at the prefix followed by a # and their index.
+type List struct {
+	Ctx    Context
+	Prefix string
+}
+
+// NewList returns a List that stores its items with the given prefix.
+func NewList(ctx Context, prefix string) List {
+	return List{Ctx: ctx, Prefix: prefix}
+}
+
+// Len returns the number of items in the list.
+func (l List) Len() int {
+	return GetInt(l.Ctx, l.Prefix)
+}
+
+// Append adds value to the end of the list.
+func (l List) Append(value interface{}) {
+	n := l.Len()
+	Put(l.Ctx, itemKey(l.Prefix+"#", n), value)
+	Put(l.Ctx, l.Prefix, n+1)
+}
+
+// Get returns the item at index i of the list.
+func (l List) Get(i int) interface{} {
+	return Get(l.Ctx, itemKey(l.Prefix+"#", i))
+}
+
+// Set replaces the item at index i of the list.
+func (l List) Set(i int, value interface{}) {
+	Put(l.Ctx, itemKey(l.Prefix+"#", i), value)
+}
+
+// Iterator returns an iterator over the items of the list. Items are not
+// guaranteed to be returned in the order of their index.
+func (l List) Iterator() iterator.Iterator {
+	return Find(l.Ctx, l.Prefix+"#")
+}
+
+// itemKey returns the given prefix followed by the bytes of i.
+//
+//neo:opcode SWAP CAT
+func itemKey(prefix string, i int) string {
+	return ""
+}
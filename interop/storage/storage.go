@@ -8,17 +8,82 @@ import "github.com/CityOfZion/neo-storm/interop/iterator"
 // Context represents the storage context
 type Context struct{}
 
+// Flag represents the flags of a value put into storage with PutEx.
+type Flag byte
+
+// Flags that can be used with PutEx.
+const (
+	// None puts the value like Put does.
+	None Flag = 0x00
+	// Constant makes the value immutable, it can not be changed or
+	// deleted afterwards.
+	Constant Flag = 0x01
+)
+
 // GetContext returns the storage context
+//
+//neo:syscall Neo.Storage.GetContext
 func GetContext() Context { return Context{} }
 
+// GetReadOnlyContext returns a storage context that can only be used to
+// read from the storage.
+//
+//neo:syscall Neo.Storage.GetReadOnlyContext
+func GetReadOnlyContext() Context { return Context{} }
+
+// AsReadOnly returns a read only copy of the storage context.
+//
+//neo:syscall Neo.StorageContext.AsReadOnly
+func (c Context) AsReadOnly() Context { return Context{} }
+
 // Put value at given key
+//
+//neo:syscall Neo.Storage.Put
 func Put(ctx Context, key interface{}, value interface{}) {}
 
+// PutEx puts value at given key with the given flags.
+//
+//neo:syscall System.Storage.PutEx
+func PutEx(ctx Context, key interface{}, value interface{}, flags Flag) {}
+
 // Get value matching given key
+//
+//neo:syscall Neo.Storage.Get
 func Get(ctx Context, key interface{}) interface{} { return 0 }
 
+// GetInt returns the value matching given key as an integer, or 0 if the
+// key does not exist.
+//
+//neo:syscall Neo.Storage.Get
+//neo:opcode PUSH0 ADD
+func GetInt(ctx Context, key interface{}) int { return 0 }
+
+// GetBytes returns the value matching given key as bytes, or an empty byte
+// slice if the key does not exist.
+//
+//neo:syscall Neo.Storage.Get
+func GetBytes(ctx Context, key interface{}) []byte { return nil }
+
+// GetString returns the value matching given key as a string, or an empty
+// string if the key does not exist.
+//
+//neo:syscall Neo.Storage.Get
+func GetString(ctx Context, key interface{}) string { return "" }
+
+// PutStruct serializes the struct value points to and puts it at given key.
+func PutStruct(ctx Context, key interface{}, value interface{}) {}
+
+// GetStruct loads the struct stored with PutStruct at given key into the
+// variable value points to, like GetStruct(ctx, key, &s). It returns false
+// if the key does not exist, in which case the variable is left unchanged.
+func GetStruct(ctx Context, key interface{}, value interface{}) bool { return false }
+
 // Delete key value pair from storage
+//
+//neo:syscall Neo.Storage.Delete
 func Delete(ctx Context, key interface{}) {}
 
 // Find returns an iterator.Iterator over the keys that matched the given key.
+//
+//neo:syscall Neo.Storage.Find
 func Find(ctx Context, key interface{}) iterator.Iterator { return iterator.Iterator{} }
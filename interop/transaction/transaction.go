@@ -13,38 +13,52 @@ import (
 type Transaction struct{}
 
 // GetHash returns the hash of the given transaction.
+//
+//neo:syscall Neo.Transaction.GetHash
 func GetHash(t Transaction) []byte {
 	return nil
 }
 
 // GetType returns the type of the given transaction.
+//
+//neo:syscall Neo.Transaction.GetType
 func GetType(t Transaction) byte {
 	return 0x00
 }
 
 // GetAttributes returns a slice of attributes for the given transaction.
+//
+//neo:syscall Neo.Transaction.GetAttributes
 func GetAttributes(t Transaction) []attribute.Attribute {
 	return []attribute.Attribute{}
 }
 
-// FIXME: What is the correct return type for this?
-// GetReferences returns a slice of references for the given transaction.
-func GetReferences(t Transaction) []interface{} {
-	return []interface{}{}
+// GetReferences returns the outputs that are spent by the inputs of the
+// given transaction.
+//
+//neo:syscall Neo.Transaction.GetReferences
+func GetReferences(t Transaction) []output.Output {
+	return []output.Output{}
 }
 
-// FIXME: What is the correct return type for this?
-// GetUnspentCoins returns the unspent coins for the given transaction.
-func GetUnspentCoins(t Transaction) interface{} {
-	return 0
+// GetUnspentCoins returns the outputs of the given transaction that are not
+// spent yet.
+//
+//neo:syscall Neo.Transaction.GetUnspentCoins
+func GetUnspentCoins(t Transaction) []output.Output {
+	return []output.Output{}
 }
 
 // GetInputs returns the inputs of the given transaction.
+//
+//neo:syscall Neo.Transaction.GetInputs
 func GetInputs(t Transaction) []input.Input {
 	return []input.Input{}
 }
 
 // GetOutputs returns the outputs of the given transaction.
+//
+//neo:syscall Neo.Transaction.GetOutputs
 func GetOutputs(t Transaction) []output.Output {
 	return []output.Output{}
 }
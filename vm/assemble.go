@@ -0,0 +1,318 @@
+package vm
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/CityOfZion/neo-go/pkg/util"
+)
+
+// mnemonics maps the names of the instructions to their opcode.
+var mnemonics = func() map[string]Instruction {
+	m := map[string]Instruction{
+		"PUSHF": PUSHF,
+		"PUSHT": PUSHT,
+	}
+	for op, name := range _Instruction_map {
+		m[name] = op
+	}
+	for op := PUSHBYTES1; op <= PUSHBYTES75; op++ {
+		m[fmt.Sprintf("PUSHBYTES%d", op)] = op
+	}
+	return m
+}()
+
+// jump is a jump or call of which the target is resolved after all labels
+// are known. Its offset is written at pos, relative to base.
+type jump struct {
+	pos   int
+	base  int
+	label string
+	line  int
+}
+
+// Assemble assembles a script from its text format, which has one
+// instruction per line in the format of Decoded.String:
+//
+//	start:
+//	    PUSHBYTES5 "hello"     ; push data is a string or 0x prefixed hex
+//	    PUSH 1000              ; pushes an integer, string or hex with the shortest instruction
+//	    SYSCALL "Neo.Runtime.Log"
+//	    JMPIF start            ; jumps go to a label or an absolute offset
+//	    APPCALL 0x5b7074e873973a6ed3708862f219a6fbf4d1c411
+//	    CALL_I 1 2 start       ; return values, parameters and the target
+//
+// Mnemonics are case insensitive, labels are not. Everything after a
+// semicolon is a comment.
+func Assemble(r io.Reader) ([]byte, error) {
+	var (
+		buf    = new(bytes.Buffer)
+		labels = map[string]int{}
+		jumps  []jump
+	)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(stripComment(scanner.Text()))
+		if i := strings.Index(text, ":"); i > 0 && !strings.ContainsAny(text[:i], " \t\"") {
+			label := text[:i]
+			if _, ok := labels[label]; ok {
+				return nil, fmt.Errorf("line %d: label %s is already defined", line, label)
+			}
+			labels[label] = buf.Len()
+			text = strings.TrimSpace(text[i+1:])
+		}
+		if text == "" {
+			continue
+		}
+
+		name, operand := text, ""
+		if i := strings.IndexAny(text, " \t"); i > 0 {
+			name, operand = text[:i], strings.TrimSpace(text[i+1:])
+		}
+		j, err := assembleInstruction(buf, strings.ToUpper(name), operand)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if j != nil {
+			j.line = line
+			jumps = append(jumps, *j)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	script := buf.Bytes()
+	for _, j := range jumps {
+		target, ok := labels[j.label]
+		if !ok {
+			return nil, fmt.Errorf("line %d: undefined label %s", j.line, j.label)
+		}
+		if err := putJumpOffset(script[j.pos:], target-j.base); err != nil {
+			return nil, fmt.Errorf("line %d: %v", j.line, err)
+		}
+	}
+	return script, nil
+}
+
+// assembleInstruction writes an instruction with its operand. It returns
+// the jump to a label, of which the offset is written when the labels are
+// known.
+func assembleInstruction(buf *bytes.Buffer, name, operand string) (*jump, error) {
+	if name == "PUSH" {
+		return nil, assemblePush(buf, operand)
+	}
+	op, ok := mnemonics[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown instruction %s", name)
+	}
+	info, _ := op.Info()
+	if info.OperandSize == 0 {
+		if operand != "" {
+			return nil, fmt.Errorf("%s takes no operand", name)
+		}
+		buf.WriteByte(byte(op))
+		return nil, nil
+	}
+	if operand == "" {
+		return nil, fmt.Errorf("%s needs an operand", name)
+	}
+
+	start := buf.Len()
+	buf.WriteByte(byte(op))
+	switch op {
+	case JMP, JMPIF, JMPIFNOT, CALL:
+		return writeJump(buf, operand, start)
+	case SYSCALL:
+		api := operand
+		if strings.HasPrefix(operand, `"`) {
+			s, err := strconv.Unquote(operand)
+			if err != nil {
+				return nil, fmt.Errorf("invalid API name %s", operand)
+			}
+			api = s
+		}
+		if len(api) == 0 || len(api) > math.MaxUint8 {
+			return nil, fmt.Errorf("invalid API name length %d", len(api))
+		}
+		buf.WriteByte(byte(len(api)))
+		buf.WriteString(api)
+		return nil, nil
+	case APPCALL, TAILCALL:
+		return nil, writeHash(buf, operand)
+	case CALL_I, CALL_E, CALL_ED, CALL_ET, CALL_EDT:
+		fields := strings.Fields(operand)
+		n := 3
+		if op == CALL_ED || op == CALL_EDT {
+			n = 2
+		}
+		if len(fields) != n {
+			return nil, fmt.Errorf("%s takes %d operands", name, n)
+		}
+		for _, f := range fields[:2] {
+			count, err := strconv.ParseUint(f, 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid count %s", f)
+			}
+			buf.WriteByte(byte(count))
+		}
+		switch op {
+		case CALL_I:
+			return writeJump(buf, fields[2], start+2)
+		case CALL_E, CALL_ET:
+			return nil, writeHash(buf, fields[2])
+		}
+		return nil, nil
+	}
+
+	data, err := parseData(operand)
+	if err != nil {
+		return nil, err
+	}
+	n := len(data)
+	switch {
+	case op <= PUSHBYTES75:
+		if n != int(op) {
+			return nil, fmt.Errorf("%s pushes %d bytes, got %d", name, op, n)
+		}
+	case op == PUSHDATA1 && n <= math.MaxUint8:
+		buf.WriteByte(byte(n))
+	case op == PUSHDATA2 && n <= math.MaxUint16:
+		b := make([]byte, 2)
+		binary.LittleEndian.PutUint16(b, uint16(n))
+		buf.Write(b)
+	case op == PUSHDATA4:
+		b := make([]byte, 4)
+		binary.LittleEndian.PutUint32(b, uint32(n))
+		buf.Write(b)
+	default:
+		return nil, fmt.Errorf("%d bytes do not fit in %s", n, name)
+	}
+	buf.Write(data)
+	return nil, nil
+}
+
+// writeJump writes the offset of a jump to an absolute offset or a label,
+// relative to base.
+func writeJump(buf *bytes.Buffer, target string, base int) (*jump, error) {
+	pos := buf.Len()
+	buf.Write([]byte{0, 0})
+	offset, err := strconv.Atoi(target)
+	if err != nil {
+		return &jump{pos: pos, base: base, label: target}, nil
+	}
+	return nil, putJumpOffset(buf.Bytes()[pos:], offset-base)
+}
+
+// writeHash writes a script hash given the way NEO shows them, with a 0x
+// prefix and the bytes in reverse order, see formatHash.
+func writeHash(buf *bytes.Buffer, operand string) error {
+	if !strings.HasPrefix(operand, "0x") {
+		return fmt.Errorf("invalid script hash %s, expected 0x prefixed hex", operand)
+	}
+	hash, err := util.Uint160DecodeString(operand[2:])
+	if err != nil {
+		return fmt.Errorf("invalid script hash %s", operand)
+	}
+	buf.Write(hash.BytesReverse())
+	return nil
+}
+
+// assemblePush writes the shortest instruction that pushes an integer, a
+// string or hex data.
+func assemblePush(buf *bytes.Buffer, operand string) error {
+	if n, ok := new(big.Int).SetString(operand, 10); ok {
+		switch {
+		case n.Sign() == 0:
+			buf.WriteByte(byte(PUSH0))
+		case n.Cmp(big.NewInt(-1)) == 0:
+			buf.WriteByte(byte(PUSHM1))
+		case n.Sign() > 0 && n.Cmp(big.NewInt(16)) <= 0:
+			buf.WriteByte(byte(PUSH1) - 1 + byte(n.Int64()))
+		default:
+			writePushData(buf, bigIntToBytes(n))
+		}
+		return nil
+	}
+	data, err := parseData(operand)
+	if err != nil {
+		return err
+	}
+	writePushData(buf, data)
+	return nil
+}
+
+func writePushData(buf *bytes.Buffer, data []byte) {
+	n := len(data)
+	switch {
+	case n == 0:
+		buf.WriteByte(byte(PUSH0))
+	case n <= int(PUSHBYTES75):
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint8:
+		buf.Write([]byte{byte(PUSHDATA1), byte(n)})
+	case n <= math.MaxUint16:
+		buf.Write([]byte{byte(PUSHDATA2), byte(n), byte(n >> 8)})
+	default:
+		buf.Write([]byte{byte(PUSHDATA4), byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24)})
+	}
+	buf.Write(data)
+}
+
+// parseData parses a quoted string or 0x prefixed hex.
+func parseData(operand string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(operand, `"`):
+		s, err := strconv.Unquote(operand)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s", operand)
+		}
+		return []byte(s), nil
+	case strings.HasPrefix(operand, "0x"):
+		b, err := hex.DecodeString(operand[2:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex %s", operand)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("invalid data %s, expected a quoted string or 0x prefixed hex", operand)
+}
+
+// putJumpOffset writes the offset of a jump relative to the instruction.
+func putJumpOffset(b []byte, offset int) error {
+	if offset < math.MinInt16 || offset > math.MaxInt16 {
+		return fmt.Errorf("jump offset %d is out of range", offset)
+	}
+	binary.LittleEndian.PutUint16(b, uint16(int16(offset)))
+	return nil
+}
+
+// stripComment removes the comment from a line, semicolons in strings do
+// not start a comment.
+func stripComment(line string) string {
+	inString := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if inString {
+				i++
+			}
+		case '"':
+			inString = !inString
+		case ';':
+			if !inString {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
@@ -0,0 +1,72 @@
+package vm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAssemble(t *testing.T) {
+	src := `
+	; a comment line
+	start:
+		PUSH 1000        ; PUSHBYTES2
+		PUSH 16
+		PUSH -1
+		PUSH "a;b"
+		pushbytes2 0x0102
+		SYSCALL "Neo.Runtime.Log"
+		jmpifnot end
+		JMP start
+	end: RET
+		APPCALL 0x14131211100f0e0d0c0b0a090807060504030201`
+
+	script, err := Assemble(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []byte{2, 0xe8, 0x03, byte(PUSH16), byte(PUSHM1), 3, 'a', ';', 'b', 2, 1, 2, byte(SYSCALL), 15}
+	expect = append(expect, "Neo.Runtime.Log"...)
+	expect = append(expect, byte(JMPIFNOT), 6, 0, byte(JMP), 0xe0, 0xff, byte(RET), byte(APPCALL))
+	// Script hashes are written in reverse order.
+	for i := 1; i <= 20; i++ {
+		expect = append(expect, byte(i))
+	}
+	if !bytes.Equal(script, expect) {
+		t.Fatalf("expected %x, got %x", expect, script)
+	}
+
+	// The disassembly assembles into the same script.
+	instrs, err := Disassemble(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, instr := range instrs {
+		lines = append(lines, instr.String())
+	}
+	again, err := Assemble(strings.NewReader(strings.Join(lines, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, script) {
+		t.Fatalf("expected %x, got %x", script, again)
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	for _, src := range []string{
+		"FOO",
+		"ADD 1",
+		"PUSHBYTES2 0x01",
+		"JMP nowhere",
+		"a: NOP\na: NOP",
+		`SYSCALL ""`,
+		"APPCALL 0x01",
+		"APPCALL 14131211100f0e0d0c0b0a090807060504030201",
+	} {
+		if _, err := Assemble(strings.NewReader(src)); err == nil {
+			t.Errorf("expected an error for %q", src)
+		}
+	}
+}
@@ -0,0 +1,492 @@
+package vm
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/CityOfZion/neo-go/pkg/util"
+)
+
+// Header is the header of a block of the in-memory blockchain.
+type Header struct {
+	Hash          util.Uint256
+	PrevHash      util.Uint256
+	Index         uint32
+	Timestamp     uint32
+	Version       uint32
+	MerkleRoot    util.Uint256
+	ConsensusData uint64
+	NextConsensus util.Uint160
+}
+
+// Block is a block of the in-memory blockchain.
+type Block struct {
+	Header
+	Transactions []*Transaction
+}
+
+// Transaction is a transaction of the in-memory blockchain.
+type Transaction struct {
+	Hash       util.Uint256
+	Type       byte
+	Attributes []*Attribute
+	Inputs     []*Input
+	Outputs    []*Output
+}
+
+// Attribute is an attribute of a transaction.
+type Attribute struct {
+	Usage byte
+	Data  []byte
+}
+
+// Input spends the output at PrevIndex of the transaction with PrevHash.
+type Input struct {
+	PrevHash  util.Uint256
+	PrevIndex uint16
+}
+
+// Output sends Value of an asset to a script hash, values are multiplied by
+// 10^8.
+type Output struct {
+	AssetID    util.Uint256
+	Value      int64
+	ScriptHash util.Uint160
+}
+
+// Contract is a contract that is deployed on the in-memory blockchain.
+type Contract struct {
+	Script     []byte
+	HasStorage bool
+	Payable    bool
+}
+
+// Blockchain is an in-memory blockchain of blocks and deployed contracts.
+type Blockchain struct {
+	Blocks    []*Block
+	Contracts map[util.Uint160]*Contract
+}
+
+// NewBlockchain returns a blockchain with only a genesis block.
+func NewBlockchain() *Blockchain {
+	c := &Blockchain{Contracts: make(map[util.Uint160]*Contract)}
+	c.AddBlock(0)
+	return c
+}
+
+// AddBlock adds a block with the given timestamp and transactions. The
+// hashes of the block and of transactions that have none are derived from
+// the index of the block.
+func (c *Blockchain) AddBlock(timestamp uint32, txs ...*Transaction) *Block {
+	b := &Block{Transactions: txs}
+	b.Index = uint32(len(c.Blocks))
+	b.Timestamp = timestamp
+	if b.Index > 0 {
+		b.PrevHash = c.Blocks[b.Index-1].Hash
+	}
+	b.Hash = mockHash(b.PrevHash[:], b.Index)
+	for i, tx := range txs {
+		if tx.Hash == (util.Uint256{}) {
+			tx.Hash = mockHash(b.Hash[:], uint32(i))
+		}
+	}
+	c.Blocks = append(c.Blocks, b)
+	return b
+}
+
+// AddContract deploys the script and returns its script hash.
+func (c *Blockchain) AddContract(script []byte, hasStorage, payable bool) util.Uint160 {
+	hash, _ := util.Uint160FromScript(script)
+	c.Contracts[hash] = &Contract{Script: script, HasStorage: hasStorage, Payable: payable}
+	return hash
+}
+
+// GetScript returns the script of the contract with the given script hash,
+// it can be used as the script getter of the VM.
+func (c *Blockchain) GetScript(hash util.Uint160) []byte {
+	if contract, ok := c.Contracts[hash]; ok {
+		return contract.Script
+	}
+	return nil
+}
+
+// Height returns the index of the last block.
+func (c *Blockchain) Height() uint32 {
+	return uint32(len(c.Blocks) - 1)
+}
+
+// GetBlock returns the block with the given hash.
+func (c *Blockchain) GetBlock(hash util.Uint256) *Block {
+	for _, b := range c.Blocks {
+		if b.Hash == hash {
+			return b
+		}
+	}
+	return nil
+}
+
+// GetTransaction returns the transaction with the given hash.
+func (c *Blockchain) GetTransaction(hash util.Uint256) *Transaction {
+	for _, b := range c.Blocks {
+		for _, tx := range b.Transactions {
+			if tx.Hash == hash {
+				return tx
+			}
+		}
+	}
+	return nil
+}
+
+// References returns the outputs spent by the inputs of the transaction.
+func (c *Blockchain) References(tx *Transaction) ([]*Output, error) {
+	outputs := make([]*Output, len(tx.Inputs))
+	for i, in := range tx.Inputs {
+		prev := c.GetTransaction(in.PrevHash)
+		if prev == nil || int(in.PrevIndex) >= len(prev.Outputs) {
+			return nil, fmt.Errorf("output %d of transaction %s not found", in.PrevIndex, in.PrevHash)
+		}
+		outputs[i] = prev.Outputs[in.PrevIndex]
+	}
+	return outputs, nil
+}
+
+// UnspentCoins returns the outputs of the transaction that are not spent
+// by a transaction in the blockchain.
+func (c *Blockchain) UnspentCoins(tx *Transaction) []*Output {
+	spent := make(map[uint16]bool)
+	for _, b := range c.Blocks {
+		for _, t := range b.Transactions {
+			for _, in := range t.Inputs {
+				if in.PrevHash == tx.Hash {
+					spent[in.PrevIndex] = true
+				}
+			}
+		}
+	}
+	var unspent []*Output
+	for i, out := range tx.Outputs {
+		if !spent[uint16(i)] {
+			unspent = append(unspent, out)
+		}
+	}
+	return unspent
+}
+
+func mockHash(prev []byte, n uint32) util.Uint256 {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, n)
+	h := sha256.Sum256(append(append([]byte{}, prev...), b...))
+	return util.Uint256(sha256.Sum256(h[:]))
+}
+
+// popValue pops an interop interface and returns its value.
+func popValue(v *VM) interface{} {
+	return v.Estack().Pop().Value()
+}
+
+func popHeader(v *VM) (*Header, error) {
+	switch t := popValue(v).(type) {
+	case *Header:
+		return t, nil
+	case *Block:
+		return &t.Header, nil
+	default:
+		return nil, fmt.Errorf("%v is not a header", t)
+	}
+}
+
+func popBlock(v *VM) (*Block, error) {
+	if b, ok := popValue(v).(*Block); ok {
+		return b, nil
+	}
+	return nil, fmt.Errorf("not a block")
+}
+
+func popTransaction(v *VM) (*Transaction, error) {
+	if tx, ok := popValue(v).(*Transaction); ok && tx != nil {
+		return tx, nil
+	}
+	return nil, fmt.Errorf("not a transaction")
+}
+
+func popOutput(v *VM) (*Output, error) {
+	if out, ok := popValue(v).(*Output); ok {
+		return out, nil
+	}
+	return nil, fmt.Errorf("not an output")
+}
+
+func popInput(v *VM) (*Input, error) {
+	if in, ok := popValue(v).(*Input); ok {
+		return in, nil
+	}
+	return nil, fmt.Errorf("not an input")
+}
+
+func popAttribute(v *VM) (*Attribute, error) {
+	if attr, ok := popValue(v).(*Attribute); ok {
+		return attr, nil
+	}
+	return nil, fmt.Errorf("not an attribute")
+}
+
+// pushInterops pushes an array of n interop interfaces with the given
+// values.
+func pushInterops(v *VM, n int, value func(i int) interface{}) {
+	items := make([]StackItem, n)
+	for i := range items {
+		items[i] = NewInteropInterface(value(i))
+	}
+	v.Estack().Push(NewArray(items))
+}
+
+// pushInterop pushes the value as an interop interface, nil pointers are
+// pushed as an empty interop interface that is false.
+func pushInterop(v *VM, value interface{}, ok bool) {
+	if !ok {
+		value = nil
+	}
+	v.Estack().Push(NewInteropInterface(value))
+}
+
+func (e *Environment) registerBlockchainFuncs(s *InteropService) {
+	s.Register("Neo.Blockchain.GetHeight", func(v *VM) error {
+		v.Estack().Push(NewIntegerInt64(int64(e.Chain.Height())))
+		return nil
+	})
+	s.Register("Neo.Blockchain.GetHeader", func(v *VM) error {
+		b := e.popBlockByIndexOrHash(v)
+		if b == nil {
+			pushInterop(v, nil, false)
+		} else {
+			pushInterop(v, &b.Header, true)
+		}
+		return nil
+	})
+	s.Register("Neo.Blockchain.GetBlock", func(v *VM) error {
+		b := e.popBlockByIndexOrHash(v)
+		pushInterop(v, b, b != nil)
+		return nil
+	})
+	s.Register("Neo.Blockchain.GetTransaction", func(v *VM) error {
+		hash, err := util.Uint256DecodeBytes(v.Estack().Pop().Bytes())
+		if err != nil {
+			return err
+		}
+		tx := e.Chain.GetTransaction(hash)
+		pushInterop(v, tx, tx != nil)
+		return nil
+	})
+	s.Register("Neo.Blockchain.GetContract", func(v *VM) error {
+		hash, err := util.Uint160DecodeBytes(v.Estack().Pop().Bytes())
+		if err != nil {
+			return err
+		}
+		contract, ok := e.Chain.Contracts[hash]
+		pushInterop(v, contract, ok)
+		return nil
+	})
+
+	headerFunc := func(f func(h *Header) StackItem) InteropFunc {
+		return func(v *VM) error {
+			h, err := popHeader(v)
+			if err != nil {
+				return err
+			}
+			v.Estack().Push(f(h))
+			return nil
+		}
+	}
+	s.Register("Neo.Header.GetIndex", headerFunc(func(h *Header) StackItem { return NewIntegerInt64(int64(h.Index)) }))
+	s.Register("Neo.Header.GetHash", headerFunc(func(h *Header) StackItem { return NewByteArray(h.Hash.Bytes()) }))
+	s.Register("Neo.Header.GetPrevHash", headerFunc(func(h *Header) StackItem { return NewByteArray(h.PrevHash.Bytes()) }))
+	s.Register("Neo.Header.GetTimestamp", headerFunc(func(h *Header) StackItem { return NewIntegerInt64(int64(h.Timestamp)) }))
+	s.Register("Neo.Header.GetVersion", headerFunc(func(h *Header) StackItem { return NewIntegerInt64(int64(h.Version)) }))
+	s.Register("Neo.Header.GetMerkleRoot", headerFunc(func(h *Header) StackItem { return NewByteArray(h.MerkleRoot.Bytes()) }))
+	s.Register("Neo.Header.GetConsensusData", headerFunc(func(h *Header) StackItem {
+		return NewInteger(new(big.Int).SetUint64(h.ConsensusData))
+	}))
+	s.Register("Neo.Header.GetNextConsensus", headerFunc(func(h *Header) StackItem { return NewByteArray(h.NextConsensus.Bytes()) }))
+
+	s.Register("Neo.Block.GetTransactionCount", func(v *VM) error {
+		b, err := popBlock(v)
+		if err != nil {
+			return err
+		}
+		v.Estack().Push(NewIntegerInt64(int64(len(b.Transactions))))
+		return nil
+	})
+	s.Register("Neo.Block.GetTransactions", func(v *VM) error {
+		b, err := popBlock(v)
+		if err != nil {
+			return err
+		}
+		pushInterops(v, len(b.Transactions), func(i int) interface{} { return b.Transactions[i] })
+		return nil
+	})
+	s.Register("Neo.Block.GetTransaction", func(v *VM) error {
+		b, err := popBlock(v)
+		if err != nil {
+			return err
+		}
+		i := v.popIndex()
+		if i >= len(b.Transactions) {
+			return fmt.Errorf("block has no transaction %d", i)
+		}
+		v.Estack().Push(NewInteropInterface(b.Transactions[i]))
+		return nil
+	})
+
+	s.Register("Neo.Transaction.GetHash", func(v *VM) error {
+		tx, err := popTransaction(v)
+		if err != nil {
+			return err
+		}
+		v.Estack().Push(NewByteArray(tx.Hash.Bytes()))
+		return nil
+	})
+	s.Register("Neo.Transaction.GetType", func(v *VM) error {
+		tx, err := popTransaction(v)
+		if err != nil {
+			return err
+		}
+		v.Estack().Push(NewByteArray([]byte{tx.Type}))
+		return nil
+	})
+	s.Register("Neo.Transaction.GetAttributes", func(v *VM) error {
+		tx, err := popTransaction(v)
+		if err != nil {
+			return err
+		}
+		pushInterops(v, len(tx.Attributes), func(i int) interface{} { return tx.Attributes[i] })
+		return nil
+	})
+	s.Register("Neo.Transaction.GetInputs", func(v *VM) error {
+		tx, err := popTransaction(v)
+		if err != nil {
+			return err
+		}
+		pushInterops(v, len(tx.Inputs), func(i int) interface{} { return tx.Inputs[i] })
+		return nil
+	})
+	s.Register("Neo.Transaction.GetOutputs", func(v *VM) error {
+		tx, err := popTransaction(v)
+		if err != nil {
+			return err
+		}
+		pushInterops(v, len(tx.Outputs), func(i int) interface{} { return tx.Outputs[i] })
+		return nil
+	})
+	s.Register("Neo.Transaction.GetReferences", func(v *VM) error {
+		tx, err := popTransaction(v)
+		if err != nil {
+			return err
+		}
+		refs, err := e.Chain.References(tx)
+		if err != nil {
+			return err
+		}
+		pushInterops(v, len(refs), func(i int) interface{} { return refs[i] })
+		return nil
+	})
+	s.Register("Neo.Transaction.GetUnspentCoins", func(v *VM) error {
+		tx, err := popTransaction(v)
+		if err != nil {
+			return err
+		}
+		unspent := e.Chain.UnspentCoins(tx)
+		pushInterops(v, len(unspent), func(i int) interface{} { return unspent[i] })
+		return nil
+	})
+
+	s.Register("Neo.Attribute.GetUsage", func(v *VM) error {
+		attr, err := popAttribute(v)
+		if err != nil {
+			return err
+		}
+		v.Estack().Push(NewByteArray([]byte{attr.Usage}))
+		return nil
+	})
+	s.Register("Neo.Attribute.GetData", func(v *VM) error {
+		attr, err := popAttribute(v)
+		if err != nil {
+			return err
+		}
+		v.Estack().Push(NewByteArray(attr.Data))
+		return nil
+	})
+	s.Register("Neo.Input.GetHash", func(v *VM) error {
+		in, err := popInput(v)
+		if err != nil {
+			return err
+		}
+		v.Estack().Push(NewByteArray(in.PrevHash.Bytes()))
+		return nil
+	})
+	s.Register("Neo.Input.GetIndex", func(v *VM) error {
+		in, err := popInput(v)
+		if err != nil {
+			return err
+		}
+		v.Estack().Push(NewIntegerInt64(int64(in.PrevIndex)))
+		return nil
+	})
+	s.Register("Neo.Output.GetAssetId", func(v *VM) error {
+		out, err := popOutput(v)
+		if err != nil {
+			return err
+		}
+		v.Estack().Push(NewByteArray(out.AssetID.Bytes()))
+		return nil
+	})
+	s.Register("Neo.Output.GetValue", func(v *VM) error {
+		out, err := popOutput(v)
+		if err != nil {
+			return err
+		}
+		v.Estack().Push(NewIntegerInt64(out.Value))
+		return nil
+	})
+	s.Register("Neo.Output.GetScriptHash", func(v *VM) error {
+		out, err := popOutput(v)
+		if err != nil {
+			return err
+		}
+		v.Estack().Push(NewByteArray(out.ScriptHash.Bytes()))
+		return nil
+	})
+
+	contractFunc := func(f func(c *Contract, hash util.Uint160) StackItem) InteropFunc {
+		return func(v *VM) error {
+			c, ok := popValue(v).(*Contract)
+			if !ok || c == nil {
+				return fmt.Errorf("not a contract")
+			}
+			hash, _ := util.Uint160FromScript(c.Script)
+			v.Estack().Push(f(c, hash))
+			return nil
+		}
+	}
+	s.Register("Neo.Contract.GetScript", contractFunc(func(c *Contract, _ util.Uint160) StackItem { return NewByteArray(c.Script) }))
+	s.Register("Neo.Contract.IsPayable", contractFunc(func(c *Contract, _ util.Uint160) StackItem { return NewBoolean(c.Payable) }))
+	s.Register("Neo.Contract.GetStorageContext", contractFunc(func(_ *Contract, hash util.Uint160) StackItem {
+		return NewInteropInterface(&StorageContext{ScriptHash: hash})
+	}))
+}
+
+// popBlockByIndexOrHash pops the index or the hash of a block and returns
+// the block, or nil if there is no such block.
+func (e *Environment) popBlockByIndexOrHash(v *VM) *Block {
+	item := v.Estack().Pop()
+	if b := item.Bytes(); len(b) == 32 {
+		hash, _ := util.Uint256DecodeBytes(b)
+		return e.Chain.GetBlock(hash)
+	}
+	n := item.BigInt()
+	if !n.IsInt64() || n.Int64() < 0 || n.Int64() >= int64(len(e.Chain.Blocks)) {
+		return nil
+	}
+	return e.Chain.Blocks[n.Int64()]
+}
@@ -0,0 +1,96 @@
+package vm
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/CityOfZion/neo-go/pkg/util"
+)
+
+// Context is the execution context of a script, it is pushed on the
+// invocation stack when a script is loaded or a function is called.
+type Context struct {
+	script []byte
+	ip     int
+
+	// estack is the evaluation stack of the context, which it shares with
+	// its caller unless it was called with an isolated stack.
+	estack *Stack
+
+	// rvcount is the number of items an isolated call returns, -1 if all
+	// the items on the evaluation stack are returned.
+	rvcount int
+}
+
+// NewContext returns a context that executes the script from its start.
+func NewContext(script []byte) *Context {
+	return &Context{script: script, rvcount: -1}
+}
+
+// Script returns the script of the context.
+func (c *Context) Script() []byte {
+	return c.script
+}
+
+// ScriptHash returns the script hash of the script of the context.
+func (c *Context) ScriptHash() util.Uint160 {
+	h, _ := util.Uint160FromScript(c.script)
+	return h
+}
+
+// IP returns the offset of the next instruction in the script.
+func (c *Context) IP() int {
+	return c.ip
+}
+
+// Next reads the next instruction and its operand and moves the instruction
+// pointer past them. The end of the script reads as RET.
+func (c *Context) Next() (Instruction, []byte, error) {
+	if c.ip >= len(c.script) {
+		return RET, nil, nil
+	}
+	op, operand, n, err := readInstruction(c.script[c.ip:])
+	if err != nil {
+		return op, nil, err
+	}
+	c.ip += n
+	return op, operand, nil
+}
+
+var errTruncated = errors.New("script ends in the middle of an instruction")
+
+// readInstruction reads the instruction at the start of script. It returns
+// the instruction, its operand and the number of bytes read. The operand of
+// a push is the pushed data, of SYSCALL the API name and of other
+// instructions the raw bytes that follow the opcode.
+func readInstruction(script []byte) (Instruction, []byte, int, error) {
+	op := Instruction(script[0])
+	info, _ := op.Info()
+	var prefix, size int
+	if info.PrefixedOperand {
+		prefix = info.OperandSize
+	} else {
+		size = info.OperandSize
+	}
+
+	n := 1
+	if prefix > 0 {
+		if len(script) < n+prefix {
+			return op, nil, 0, errTruncated
+		}
+		b := script[n : n+prefix]
+		switch prefix {
+		case 1:
+			size = int(b[0])
+		case 2:
+			size = int(binary.LittleEndian.Uint16(b))
+		case 4:
+			size = int(binary.LittleEndian.Uint32(b))
+		}
+		n += prefix
+	}
+	if size < 0 || len(script)-n < size {
+		return op, nil, 0, errTruncated
+	}
+	return op, script[n : n+size], n + size, nil
+}
@@ -0,0 +1,122 @@
+package vm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+
+	"github.com/CityOfZion/neo-go/pkg/util"
+)
+
+// Decoded is an instruction of a script along with its decoded operand.
+type Decoded struct {
+	// Offset of the instruction in the script.
+	Offset int
+
+	// Size of the instruction in bytes, including its operand.
+	Size int
+
+	Op Instruction
+
+	// Operand holds the data of a push, the API name of SYSCALL and the
+	// raw bytes that follow the opcode of other instructions.
+	Operand []byte
+
+	// Target is the absolute offset a jump or a call within the script
+	// goes to.
+	Target int
+}
+
+// Disassemble decodes the instructions of a script. The instructions that
+// could be decoded are returned along with the error when the script ends
+// in the middle of an instruction.
+func Disassemble(script []byte) ([]Decoded, error) {
+	var instrs []Decoded
+	for offset := 0; offset < len(script); {
+		op, operand, n, err := readInstruction(script[offset:])
+		if err != nil {
+			return instrs, fmt.Errorf("%s at offset %d: %v", op, offset, err)
+		}
+		d := Decoded{
+			Offset:  offset,
+			Size:    n,
+			Op:      op,
+			Operand: operand,
+		}
+		switch {
+		case op == CALL_I:
+			// The offset is relative to the byte before it, like the one
+			// of a JMP.
+			d.Target = offset + 2 + int(int16(binary.LittleEndian.Uint16(operand[2:])))
+		case d.IsJump():
+			d.Target = offset + int(int16(binary.LittleEndian.Uint16(operand)))
+		}
+		instrs = append(instrs, d)
+		offset += n
+	}
+	return instrs, nil
+}
+
+// IsJump reports whether the instruction is a jump or a call within the script.
+func (d Decoded) IsJump() bool {
+	switch d.Op {
+	case JMP, JMPIF, JMPIFNOT, CALL, CALL_I:
+		return true
+	}
+	return false
+}
+
+// IsPush reports whether the instruction pushes the data of its operand.
+func (d Decoded) IsPush() bool {
+	return d.Op >= PUSHBYTES1 && d.Op <= PUSHDATA4
+}
+
+// Name returns the mnemonic of the instruction.
+func (d Decoded) Name() string {
+	if d.Op > PUSHBYTES1 && d.Op < PUSHBYTES75 {
+		return fmt.Sprintf("PUSHBYTES%d", d.Op)
+	}
+	return d.Op.String()
+}
+
+// OperandString returns the operand in a readable format: push data in hex,
+// the quoted API name of a syscall, the script hash of an app call and the
+// absolute target of a jump. The calls with an isolated stack start with the
+// number of return values and parameters. It is empty for instructions
+// without operand.
+func (d Decoded) OperandString() string {
+	switch d.Op {
+	case SYSCALL:
+		return strconv.Quote(string(d.Operand))
+	case APPCALL, TAILCALL:
+		return formatHash(d.Operand)
+	case CALL_I:
+		return fmt.Sprintf("%d %d %d", d.Operand[0], d.Operand[1], d.Target)
+	case CALL_E, CALL_ET:
+		return fmt.Sprintf("%d %d %s", d.Operand[0], d.Operand[1], formatHash(d.Operand[2:]))
+	case CALL_ED, CALL_EDT:
+		return fmt.Sprintf("%d %d", d.Operand[0], d.Operand[1])
+	}
+	switch {
+	case d.IsPush():
+		return fmt.Sprintf("0x%x", d.Operand)
+	case d.IsJump():
+		return strconv.Itoa(d.Target)
+	}
+	return ""
+}
+
+// formatHash formats a script hash the way NEO shows them, with a 0x prefix
+// and the bytes in reverse order.
+func formatHash(b []byte) string {
+	hash, _ := util.Uint160DecodeBytes(b)
+	return fmt.Sprintf("0x%x", hash.BytesReverse())
+}
+
+// String returns the mnemonic of the instruction followed by its operand.
+func (d Decoded) String() string {
+	if s := d.OperandString(); s != "" {
+		return d.Name() + " " + s
+	}
+	return d.Name()
+}
@@ -0,0 +1,44 @@
+package vm
+
+import "testing"
+
+func TestDisassemble(t *testing.T) {
+	script := []byte{byte(PUSH1), byte(JMPIF), 5, 0, 2, 'o', 'k', byte(SYSCALL), 3, 'f', 'o', 'o'}
+	script = append(script, byte(PUSHDATA1), 1, 0xff, byte(JMP), 0xf5, 0xff, byte(APPCALL))
+	for i := 1; i <= 20; i++ {
+		script = append(script, byte(i))
+	}
+
+	instrs, err := Disassemble(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		"PUSH1",
+		"JMPIF 6",
+		"PUSHBYTES2 0x6f6b",
+		`SYSCALL "foo"`,
+		"PUSHDATA1 0xff",
+		"JMP 4",
+		// Script hashes are shown in reverse order.
+		"APPCALL 0x14131211100f0e0d0c0b0a090807060504030201",
+	}
+	if len(instrs) != len(expect) {
+		t.Fatalf("expected %d instructions, got %d: %v", len(expect), len(instrs), instrs)
+	}
+	offset := 0
+	for i, instr := range instrs {
+		if instr.String() != expect[i] {
+			t.Errorf("expected %s, got %s", expect[i], instr)
+		}
+		if instr.Offset != offset {
+			t.Errorf("%s: expected offset %d, got %d", instr, offset, instr.Offset)
+		}
+		offset += instr.Size
+	}
+
+	instrs, err = Disassemble([]byte{byte(PUSH1), byte(PUSHDATA2), 5, 0, 1})
+	if err == nil || len(instrs) != 1 {
+		t.Fatalf("expected an error after 1 instruction, got %v and %v", instrs, err)
+	}
+}
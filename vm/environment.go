@@ -0,0 +1,324 @@
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/CityOfZion/neo-go/pkg/util"
+)
+
+// Triggers a contract can be invoked with.
+const (
+	TriggerVerification  byte = 0x00
+	TriggerVerificationR byte = 0x01
+	TriggerApplication   byte = 0x10
+	TriggerApplicationR  byte = 0x11
+)
+
+// Triggers maps the names of the triggers to their values.
+var Triggers = map[string]byte{
+	"verification":  TriggerVerification,
+	"verificationR": TriggerVerificationR,
+	"application":   TriggerApplication,
+	"applicationR":  TriggerApplicationR,
+}
+
+// Flags of the storage items written with System.Storage.PutEx.
+const (
+	StorageNone     byte = 0x00
+	StorageConstant byte = 0x01
+)
+
+// maxStorageKeySize is the maximum size of a storage key.
+const maxStorageKeySize = 1024
+
+// Environment is an in-memory blockchain that contracts are executed
+// against. It implements the runtime, engine, storage, iterator and
+// blockchain syscalls, the syscalls that change the blockchain state, like
+// the ones of the asset and account packages, are not implemented.
+type Environment struct {
+	// Trigger is returned by Neo.Runtime.GetTrigger.
+	Trigger byte
+	// Time is the timestamp of the block the contract executes in.
+	Time uint32
+	// Witnesses are the script hashes Neo.Runtime.CheckWitness returns
+	// true for.
+	Witnesses []util.Uint160
+	// Container is the transaction that invokes the contract.
+	Container *Transaction
+
+	// Storage holds the storage items of the contracts by their script
+	// hash and key.
+	Storage map[util.Uint160]map[string]*StorageItem
+	// Chain holds the blocks and the deployed contracts.
+	Chain *Blockchain
+
+	// Logs are the messages of Neo.Runtime.Log.
+	Logs []string
+	// Notifications are the items of Neo.Runtime.Notify.
+	Notifications []Notification
+}
+
+// StorageItem is a value in the storage of a contract.
+type StorageItem struct {
+	Value []byte
+	// Constant items can not be changed or deleted.
+	Constant bool
+}
+
+// StorageContext is the value of the storage contexts that the storage
+// syscalls take.
+type StorageContext struct {
+	ScriptHash util.Uint160
+	ReadOnly   bool
+}
+
+// Notification is an item that a contract notified.
+type Notification struct {
+	ScriptHash util.Uint160
+	Item       StackItem
+}
+
+// NewEnvironment returns an environment with empty storage and a blockchain
+// with only a genesis block, contracts are invoked with the application
+// trigger.
+func NewEnvironment() *Environment {
+	return &Environment{
+		Trigger: TriggerApplication,
+		Storage: make(map[util.Uint160]map[string]*StorageItem),
+		Chain:   NewBlockchain(),
+	}
+}
+
+// Attach makes the VM execute its scripts against the environment: it
+// registers the syscalls of the environment and resolves contract calls
+// with the contracts of its blockchain.
+func (e *Environment) Attach(v *VM) {
+	v.SetInteropService(e.InteropService())
+	v.SetScriptGetter(e.Chain.GetScript)
+}
+
+// InteropService returns a registry with the syscalls of the environment.
+// Register other implementations on it to override them.
+func (e *Environment) InteropService() *InteropService {
+	s := NewInteropService()
+	e.registerRuntimeFuncs(s)
+	e.registerEngineFuncs(s)
+	e.registerStorageFuncs(s)
+	e.registerBlockchainFuncs(s)
+	registerIteratorFuncs(s)
+	return s
+}
+
+// Get returns the value stored by the contract with the given script hash,
+// or nil if there is none.
+func (e *Environment) Get(hash util.Uint160, key []byte) []byte {
+	if item, ok := e.Storage[hash][string(key)]; ok {
+		return item.Value
+	}
+	return nil
+}
+
+// Put stores the value for the contract with the given script hash.
+func (e *Environment) Put(hash util.Uint160, key, value []byte) {
+	if e.Storage[hash] == nil {
+		e.Storage[hash] = make(map[string]*StorageItem)
+	}
+	e.Storage[hash][string(key)] = &StorageItem{Value: value}
+}
+
+func (e *Environment) registerRuntimeFuncs(s *InteropService) {
+	s.Register("Neo.Runtime.Log", func(v *VM) error {
+		e.Logs = append(e.Logs, string(v.Estack().Pop().Bytes()))
+		return nil
+	})
+	s.Register("Neo.Runtime.Notify", func(v *VM) error {
+		e.Notifications = append(e.Notifications, Notification{
+			ScriptHash: v.Context().ScriptHash(),
+			Item:       v.Estack().Pop(),
+		})
+		return nil
+	})
+	s.Register("Neo.Runtime.CheckWitness", func(v *VM) error {
+		b := v.Estack().Pop().Bytes()
+		var hash util.Uint160
+		switch len(b) {
+		case 20:
+			hash, _ = util.Uint160DecodeBytes(b)
+		case 33:
+			// The script hash of the signature contract of the public key.
+			script := append(append([]byte{byte(PUSHBYTES1) + 32}, b...), byte(CHECKSIG))
+			hash, _ = util.Uint160FromScript(script)
+		default:
+			return fmt.Errorf("invalid script hash or public key %x", b)
+		}
+		ok := false
+		for _, w := range e.Witnesses {
+			ok = ok || w == hash
+		}
+		v.Estack().Push(NewBoolean(ok))
+		return nil
+	})
+	s.Register("Neo.Runtime.GetTime", func(v *VM) error {
+		v.Estack().Push(NewIntegerInt64(int64(e.Time)))
+		return nil
+	})
+	s.Register("Neo.Runtime.GetTrigger", func(v *VM) error {
+		v.Estack().Push(NewIntegerInt64(int64(e.Trigger)))
+		return nil
+	})
+	s.Register("Neo.Runtime.Serialize", func(v *VM) error {
+		b, err := SerializeItem(v.Estack().Pop())
+		if err != nil {
+			return err
+		}
+		v.Estack().Push(NewByteArray(b))
+		return nil
+	})
+	s.Register("Neo.Runtime.Deserialize", func(v *VM) error {
+		item, err := DeserializeItem(v.Estack().Pop().Bytes())
+		if err != nil {
+			return err
+		}
+		v.Estack().Push(item)
+		return nil
+	})
+}
+
+func (e *Environment) registerEngineFuncs(s *InteropService) {
+	pushHash := func(v *VM, ctx *Context) {
+		if ctx == nil {
+			v.Estack().Push(NewByteArray([]byte{}))
+			return
+		}
+		v.Estack().Push(NewByteArray(ctx.ScriptHash().Bytes()))
+	}
+	s.Register("System.ExecutionEngine.GetScriptContainer", func(v *VM) error {
+		pushInterop(v, e.Container, e.Container != nil)
+		return nil
+	})
+	s.Register("System.ExecutionEngine.GetExecutingScriptHash", func(v *VM) error {
+		pushHash(v, v.Context())
+		return nil
+	})
+	s.Register("System.ExecutionEngine.GetCallingScriptHash", func(v *VM) error {
+		pushHash(v, v.CallingContext())
+		return nil
+	})
+	s.Register("System.ExecutionEngine.GetEntryScriptHash", func(v *VM) error {
+		pushHash(v, v.EntryContext())
+		return nil
+	})
+}
+
+func popStorageContext(v *VM) (*StorageContext, error) {
+	if ctx, ok := popValue(v).(*StorageContext); ok {
+		return ctx, nil
+	}
+	return nil, errors.New("not a storage context")
+}
+
+// popWritableStorage pops a storage context and a key for a syscall that
+// changes the storage, it returns the storage of the contract and the key.
+func (e *Environment) popWritableStorage(v *VM) (map[string]*StorageItem, string, error) {
+	ctx, err := popStorageContext(v)
+	if err != nil {
+		return nil, "", err
+	}
+	if ctx.ReadOnly {
+		return nil, "", errors.New("storage context is read only")
+	}
+	key := v.Estack().Pop().Bytes()
+	if len(key) > maxStorageKeySize {
+		return nil, "", fmt.Errorf("storage key is larger than %d bytes", maxStorageKeySize)
+	}
+	if e.Storage[ctx.ScriptHash] == nil {
+		e.Storage[ctx.ScriptHash] = make(map[string]*StorageItem)
+	}
+	storage := e.Storage[ctx.ScriptHash]
+	if item, ok := storage[string(key)]; ok && item.Constant {
+		return nil, "", fmt.Errorf("storage item %x is constant", key)
+	}
+	return storage, string(key), nil
+}
+
+func (e *Environment) registerStorageFuncs(s *InteropService) {
+	s.Register("Neo.Storage.GetContext", func(v *VM) error {
+		v.Estack().Push(NewInteropInterface(&StorageContext{ScriptHash: v.Context().ScriptHash()}))
+		return nil
+	})
+	s.Register("Neo.Storage.GetReadOnlyContext", func(v *VM) error {
+		v.Estack().Push(NewInteropInterface(&StorageContext{ScriptHash: v.Context().ScriptHash(), ReadOnly: true}))
+		return nil
+	})
+	s.Register("Neo.StorageContext.AsReadOnly", func(v *VM) error {
+		ctx, err := popStorageContext(v)
+		if err != nil {
+			return err
+		}
+		v.Estack().Push(NewInteropInterface(&StorageContext{ScriptHash: ctx.ScriptHash, ReadOnly: true}))
+		return nil
+	})
+	s.Register("Neo.Storage.Get", func(v *VM) error {
+		ctx, err := popStorageContext(v)
+		if err != nil {
+			return err
+		}
+		value := e.Get(ctx.ScriptHash, v.Estack().Pop().Bytes())
+		if value == nil {
+			value = []byte{}
+		}
+		v.Estack().Push(NewByteArray(value))
+		return nil
+	})
+	s.Register("Neo.Storage.Put", func(v *VM) error {
+		storage, key, err := e.popWritableStorage(v)
+		if err != nil {
+			return err
+		}
+		storage[key] = &StorageItem{Value: v.Estack().Pop().Bytes()}
+		return nil
+	})
+	s.Register("System.Storage.PutEx", func(v *VM) error {
+		storage, key, err := e.popWritableStorage(v)
+		if err != nil {
+			return err
+		}
+		value := v.Estack().Pop().Bytes()
+		flags := v.Estack().Pop().BigInt().Int64()
+		storage[key] = &StorageItem{Value: value, Constant: byte(flags)&StorageConstant != 0}
+		return nil
+	})
+	s.Register("Neo.Storage.Delete", func(v *VM) error {
+		storage, key, err := e.popWritableStorage(v)
+		if err != nil {
+			return err
+		}
+		delete(storage, key)
+		return nil
+	})
+	s.Register("Neo.Storage.Find", func(v *VM) error {
+		ctx, err := popStorageContext(v)
+		if err != nil {
+			return err
+		}
+		prefix := v.Estack().Pop().Bytes()
+		var found []string
+		for key := range e.Storage[ctx.ScriptHash] {
+			if bytes.HasPrefix([]byte(key), prefix) {
+				found = append(found, key)
+			}
+		}
+		sort.Strings(found)
+		keys := make([]StackItem, len(found))
+		values := make([]StackItem, len(found))
+		for i, key := range found {
+			keys[i] = NewByteArray([]byte(key))
+			values[i] = NewByteArray(e.Storage[ctx.ScriptHash][key].Value)
+		}
+		v.Estack().Push(NewInteropInterface(newIterator(keys, values)))
+		return nil
+	})
+}
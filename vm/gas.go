@@ -0,0 +1,162 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/CityOfZion/neo-go/pkg/util"
+)
+
+// FreeGas is the GAS an invocation can consume without paying for it.
+var FreeGas = util.NewFixed8(10)
+
+// gasRatio is the GAS of one unit of the instruction and syscall prices,
+// which are in 0.001 GAS.
+const gasRatio util.Fixed8 = 100000
+
+// Limits are the execution limits of the VM, exceeding them makes the VM
+// fault. Limits that are 0 are not checked.
+type Limits struct {
+	// MaxStackSize is the maximum number of items on the evaluation and
+	// alt stack together.
+	MaxStackSize int
+	// MaxItemSize is the maximum size of a byte array in bytes.
+	MaxItemSize int
+	// MaxArraySize is the maximum number of items of an array, struct or
+	// map.
+	MaxArraySize int
+	// MaxInvocationStackSize is the maximum number of contexts on the
+	// invocation stack.
+	MaxInvocationStackSize int
+}
+
+// DefaultLimits are the limits of the NEO network.
+var DefaultLimits = Limits{
+	MaxStackSize:           2 * 1024,
+	MaxItemSize:            1024 * 1024,
+	MaxArraySize:           1024,
+	MaxInvocationStackSize: 1024,
+}
+
+// syscallPrices are the prices of the syscalls that do not cost 1.
+var syscallPrices = map[string]int64{
+	"Neo.Runtime.CheckWitness":        200,
+	"Neo.Blockchain.GetHeader":        100,
+	"Neo.Blockchain.GetBlock":         200,
+	"Neo.Blockchain.GetTransaction":   100,
+	"Neo.Blockchain.GetAccount":       100,
+	"Neo.Blockchain.GetValidators":    200,
+	"Neo.Blockchain.GetAsset":         100,
+	"Neo.Blockchain.GetContract":      100,
+	"Neo.Transaction.GetReferences":   200,
+	"Neo.Transaction.GetUnspentCoins": 200,
+	"Neo.Asset.Create":                5000 * 1000,
+	"Neo.Asset.Renew":                 5000 * 1000,
+	"Neo.Contract.Create":             100 * 1000,
+	"Neo.Contract.Migrate":            100 * 1000,
+	"Neo.Storage.Get":                 100,
+	"Neo.Storage.Delete":              100,
+}
+
+// price returns the price of the instruction in 0.001 GAS.
+func (v *VM) price(op Instruction, operand []byte) int64 {
+	switch op {
+	case CHECKMULTISIG:
+		// The number of public keys is on top of the stack, or the length
+		// of the array of public keys.
+		if v.estack.Len() == 0 {
+			return 1
+		}
+		n := 0
+		if items, ok := arrayItems(v.estack.Peek(0)); ok {
+			n = len(items)
+		} else if i := v.estack.Peek(0).BigInt(); i.IsInt64() {
+			n = int(i.Int64())
+		}
+		if n < 1 {
+			return 1
+		}
+		return int64(n) * 100
+	case SYSCALL:
+		return v.syscallPrice(string(operand))
+	}
+	if info, ok := op.Info(); ok {
+		return info.Price
+	}
+	return 1
+}
+
+func (v *VM) syscallPrice(api string) int64 {
+	switch api {
+	case "Neo.Storage.Put", "System.Storage.PutEx":
+		// Storage is paid per started KB of the key and value.
+		if v.estack.Len() < 3 {
+			return 1
+		}
+		size := len(v.estack.Peek(1).Bytes()) + len(v.estack.Peek(2).Bytes())
+		return int64((size-1)/1024+1) * 1000
+	case "Neo.Asset.Renew":
+		// The asset is renewed for the number of years under it.
+		if v.estack.Len() < 2 {
+			return 1
+		}
+		return v.estack.Peek(1).BigInt().Int64() * syscallPrices[api]
+	}
+	if price, ok := syscallPrices[api]; ok {
+		return price
+	}
+	return 1
+}
+
+// consumeGas adds the price of the instruction to the consumed GAS.
+func (v *VM) consumeGas(op Instruction, operand []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	v.gasConsumed += util.Fixed8(v.price(op, operand)) * gasRatio
+	if v.gasLimit > 0 && v.gasConsumed > v.gasLimit {
+		return fmt.Errorf("GAS limit of %s exceeded", v.gasLimit)
+	}
+	return nil
+}
+
+// checkLimits checks the limits that apply after every instruction.
+func (v *VM) checkLimits() error {
+	if max := v.limits.MaxStackSize; max > 0 && v.stackSize() > max {
+		return fmt.Errorf("stack size exceeds %d items", max)
+	}
+	if max := v.limits.MaxInvocationStackSize; max > 0 && len(v.istack) > max {
+		return fmt.Errorf("invocation stack size exceeds %d contexts", max)
+	}
+	return nil
+}
+
+// stackSize returns the number of items on the evaluation stacks of the
+// loaded contexts and on the alt stack.
+func (v *VM) stackSize() int {
+	n := v.astack.Len() + v.rstack.Len()
+	last := v.rstack
+	for _, ctx := range v.istack {
+		if ctx.estack != last {
+			n += ctx.estack.Len()
+			last = ctx.estack
+		}
+	}
+	return n
+}
+
+// checkItemSize panics if the byte array is larger than the maximum item
+// size.
+func (v *VM) checkItemSize(n int) {
+	if max := v.limits.MaxItemSize; max > 0 && n > max {
+		panic(fmt.Sprintf("item size of %d bytes exceeds %d bytes", n, max))
+	}
+}
+
+// checkArraySize panics if the array is larger than the maximum array size.
+func (v *VM) checkArraySize(n int) {
+	if max := v.limits.MaxArraySize; max > 0 && n > max {
+		panic(fmt.Sprintf("array size of %d exceeds %d items", n, max))
+	}
+}
@@ -0,0 +1,77 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/CityOfZion/neo-go/pkg/util"
+)
+
+func TestGasConsumed(t *testing.T) {
+	// PUSH1 PUSH2 ADD SHA256 costs 0 + 0 + 1 + 10 and the end of the script
+	// reads as RET which costs 1.
+	v := New()
+	v.LoadScript([]byte{byte(PUSH1), byte(PUSH2), byte(ADD), byte(SHA256)})
+	if err := v.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if gas := v.GasConsumed(); gas != 12*gasRatio {
+		t.Fatalf("expected 0.012 GAS, got %s", gas)
+	}
+
+	// Storing up to 1KB costs 1 GAS.
+	env := NewEnvironment()
+	v = New()
+	env.Attach(v)
+	script := []byte{byte(PUSH5), byte(PUSH1) + 2}
+	script = append(script, syscall("Neo.Storage.GetContext")...)
+	script = append(script, syscall("Neo.Storage.Put")...)
+	v.LoadScript(script)
+	if err := v.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if gas := v.GasConsumed(); gas != util.NewFixed8(1)+2*gasRatio {
+		t.Fatalf("expected 1.002 GAS, got %s", gas)
+	}
+}
+
+func TestGasLimit(t *testing.T) {
+	// An endless loop.
+	v := New()
+	v.SetGasLimit(FreeGas)
+	v.LoadScript([]byte{byte(NOP), byte(PUSH1), byte(DROP), byte(JMP), 0xfe, 0xff})
+	err := v.Run()
+	if err == nil || v.State() != FaultState || !strings.Contains(err.Error(), "GAS limit") {
+		t.Fatalf("expected the GAS limit to be exceeded, got %v", err)
+	}
+	if v.GasConsumed() <= FreeGas {
+		t.Fatalf("expected more than %s GAS to be consumed, got %s", FreeGas, v.GasConsumed())
+	}
+}
+
+func TestLimits(t *testing.T) {
+	var cases = []struct {
+		name   string
+		script []byte
+	}{
+		{"array size", []byte{byte(PUSHBYTES1) + 1, 0x01, 0x04, byte(NEWARRAY)}},
+		{"item size", []byte{byte(PUSH1), byte(DUP), byte(CAT), byte(DUP), byte(JMP), 0xfd, 0xff}},
+		{"stack size", []byte{byte(PUSH1), byte(DUP), byte(JMP), 0xff, 0xff}},
+		{"integer size", []byte{byte(PUSH1), byte(PUSHBYTES1) + 1, 0xff, 0x00, byte(SHL)}},
+		{"invocation stack size", []byte{byte(CALL), 0x00, 0x00}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			v := New()
+			v.LoadScript(c.script)
+			if err := v.Run(); err == nil || !strings.Contains(err.Error(), c.name) {
+				t.Fatalf("expected the %s to be exceeded, got %v", c.name, err)
+			}
+		})
+	}
+}
+
+func syscall(api string) []byte {
+	return append([]byte{byte(SYSCALL), byte(len(api))}, api...)
+}
@@ -4,7 +4,7 @@ package vm
 
 import "strconv"
 
-const _Instruction_name = "PUSH0PUSHBYTES1PUSHBYTES75PUSHDATA1PUSHDATA2PUSHDATA4PUSHM1PUSH1PUSH2PUSH3PUSH4PUSH5PUSH6PUSH7PUSH8PUSH9PUSH10PUSH11PUSH12PUSH13PUSH14PUSH15PUSH16NOPJMPJMPIFJMPIFNOTCALLRETAPPCALLSYSCALLTAILCALLDUPFROMALTSTACKTOALTSTACKFROMALTSTACKXDROPXSWAPXTUCKDEPTHDROPDUPNIPOVERPICKROLLROTSWAPTUCKCATSUBSTRLEFTRIGHTSIZEINVERTANDORXOREQUALINCDECSIGNNEGATEABSNOTNZADDSUBMULDIVMODSHLSHRBOOLANDBOOLORNUMEQUALNUMNOTEQUALLTGTLTEGTEMINMAXWITHINSHA1SHA256HASH160HASH256CHECKSIGCHECKMULTISIGARRAYSIZEPACKUNPACKPICKITEMSETITEMNEWARRAYNEWSTRUCTAPPENDREVERSEREMOVETHROWTHROWIFNOT"
+const _Instruction_name = "PUSH0PUSHBYTES1PUSHBYTES75PUSHDATA1PUSHDATA2PUSHDATA4PUSHM1PUSH1PUSH2PUSH3PUSH4PUSH5PUSH6PUSH7PUSH8PUSH9PUSH10PUSH11PUSH12PUSH13PUSH14PUSH15PUSH16NOPJMPJMPIFJMPIFNOTCALLRETAPPCALLSYSCALLTAILCALLDUPFROMALTSTACKTOALTSTACKFROMALTSTACKXDROPXSWAPXTUCKDEPTHDROPDUPNIPOVERPICKROLLROTSWAPTUCKCATSUBSTRLEFTRIGHTSIZEINVERTANDORXOREQUALINCDECSIGNNEGATEABSNOTNZADDSUBMULDIVMODSHLSHRBOOLANDBOOLORNUMEQUALNUMNOTEQUALLTGTLTEGTEMINMAXWITHINSHA1SHA256HASH160HASH256CHECKSIGVERIFYCHECKMULTISIGARRAYSIZEPACKUNPACKPICKITEMSETITEMNEWARRAYNEWSTRUCTNEWMAPAPPENDREVERSEREMOVEHASKEYKEYSVALUESCALL_ICALL_ECALL_EDCALL_ETCALL_EDTTHROWTHROWIFNOT"
 
 var _Instruction_map = map[Instruction]string{
 	0:   _Instruction_name[0:5],
@@ -95,19 +95,29 @@ var _Instruction_map = map[Instruction]string{
 	169: _Instruction_name[434:441],
 	170: _Instruction_name[441:448],
 	172: _Instruction_name[448:456],
-	174: _Instruction_name[456:469],
-	192: _Instruction_name[469:478],
-	193: _Instruction_name[478:482],
-	194: _Instruction_name[482:488],
-	195: _Instruction_name[488:496],
-	196: _Instruction_name[496:503],
-	197: _Instruction_name[503:511],
-	198: _Instruction_name[511:520],
-	200: _Instruction_name[520:526],
-	201: _Instruction_name[526:533],
-	202: _Instruction_name[533:539],
-	240: _Instruction_name[539:544],
-	241: _Instruction_name[544:554],
+	173: _Instruction_name[456:462],
+	174: _Instruction_name[462:475],
+	192: _Instruction_name[475:484],
+	193: _Instruction_name[484:488],
+	194: _Instruction_name[488:494],
+	195: _Instruction_name[494:502],
+	196: _Instruction_name[502:509],
+	197: _Instruction_name[509:517],
+	198: _Instruction_name[517:526],
+	199: _Instruction_name[526:532],
+	200: _Instruction_name[532:538],
+	201: _Instruction_name[538:545],
+	202: _Instruction_name[545:551],
+	203: _Instruction_name[551:557],
+	204: _Instruction_name[557:561],
+	205: _Instruction_name[561:567],
+	224: _Instruction_name[567:573],
+	225: _Instruction_name[573:579],
+	226: _Instruction_name[579:586],
+	227: _Instruction_name[586:593],
+	228: _Instruction_name[593:601],
+	240: _Instruction_name[601:606],
+	241: _Instruction_name[606:616],
 }
 
 func (i Instruction) String() string {
@@ -0,0 +1,42 @@
+package vm
+
+import "fmt"
+
+// InteropFunc is the Go implementation of a syscall. It takes its arguments
+// from the evaluation stack of the VM and pushes its result on it.
+type InteropFunc func(v *VM) error
+
+// InteropService is a registry of syscall implementations, keyed by their API
+// name like Neo.Storage.Put.
+type InteropService struct {
+	funcs map[string]InteropFunc
+}
+
+// NewInteropService returns a registry without any syscalls.
+func NewInteropService() *InteropService {
+	return &InteropService{funcs: make(map[string]InteropFunc)}
+}
+
+// Register registers the implementation of the syscall with the given API
+// name, it replaces the implementation that was registered before.
+func (s *InteropService) Register(api string, f InteropFunc) {
+	s.funcs[api] = f
+}
+
+// Get returns the implementation of the syscall with the given API name.
+func (s *InteropService) Get(api string) (InteropFunc, bool) {
+	f, ok := s.funcs[api]
+	return f, ok
+}
+
+// Wrap replaces the implementation of a registered syscall with the result
+// of wrap, so a test can inspect the calls or change the result and still
+// call the original implementation.
+func (s *InteropService) Wrap(api string, wrap func(InteropFunc) InteropFunc) error {
+	f, ok := s.funcs[api]
+	if !ok {
+		return fmt.Errorf("syscall %s is not registered", api)
+	}
+	s.funcs[api] = wrap(f)
+	return nil
+}
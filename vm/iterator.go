@@ -0,0 +1,148 @@
+package vm
+
+import "fmt"
+
+// iterator is the value of the iterators and enumerators that the iterator
+// and enumerator syscalls return. Enumerators are iterators of which the
+// keys are not used.
+type iterator struct {
+	keys   []StackItem
+	values []StackItem
+	index  int
+}
+
+func newIterator(keys, values []StackItem) *iterator {
+	return &iterator{keys: keys, values: values, index: -1}
+}
+
+// newArrayIterator returns an iterator over the items of an array or the
+// elements of a map, arrays are indexed by integers.
+func newArrayIterator(item StackItem) (*iterator, error) {
+	if m, ok := item.(*Map); ok {
+		return newIterator(m.Keys(), m.Values()), nil
+	}
+	items, ok := arrayItems(item)
+	if !ok {
+		return nil, fmt.Errorf("can not iterate over %s", item)
+	}
+	keys := make([]StackItem, len(items))
+	for i := range keys {
+		keys[i] = NewIntegerInt64(int64(i))
+	}
+	return newIterator(keys, append([]StackItem{}, items...)), nil
+}
+
+func (it *iterator) next() bool {
+	if it.index < len(it.values) {
+		it.index++
+	}
+	return it.index < len(it.values)
+}
+
+func (it *iterator) current() (StackItem, StackItem, error) {
+	if it.index < 0 || it.index >= len(it.values) {
+		return nil, nil, fmt.Errorf("iterator is not at an item")
+	}
+	return it.keys[it.index], it.values[it.index], nil
+}
+
+// rest returns the keys and values that have not been iterated over yet.
+func (it *iterator) rest() ([]StackItem, []StackItem) {
+	start := it.index + 1
+	if start > len(it.values) {
+		start = len(it.values)
+	}
+	return it.keys[start:], it.values[start:]
+}
+
+func popIterator(v *VM) (*iterator, error) {
+	item := v.Estack().Pop()
+	if i, ok := item.Value().(*iterator); ok {
+		return i, nil
+	}
+	return nil, fmt.Errorf("%s is not an iterator", item)
+}
+
+func registerIteratorFuncs(s *InteropService) {
+	create := func(v *VM) error {
+		it, err := newArrayIterator(v.Estack().Pop())
+		if err != nil {
+			return err
+		}
+		v.Estack().Push(NewInteropInterface(it))
+		return nil
+	}
+	next := func(v *VM) error {
+		it, err := popIterator(v)
+		if err != nil {
+			return err
+		}
+		v.Estack().Push(NewBoolean(it.next()))
+		return nil
+	}
+	value := func(v *VM) error {
+		it, err := popIterator(v)
+		if err != nil {
+			return err
+		}
+		_, value, err := it.current()
+		if err != nil {
+			return err
+		}
+		v.Estack().Push(value)
+		return nil
+	}
+
+	s.Register("Neo.Iterator.Create", create)
+	s.Register("Neo.Enumerator.Create", create)
+	s.Register("Neo.Iterator.Next", next)
+	s.Register("Neo.Enumerator.Next", next)
+	s.Register("Neo.Iterator.Value", value)
+	s.Register("Neo.Enumerator.Value", value)
+	s.Register("Neo.Iterator.Key", func(v *VM) error {
+		it, err := popIterator(v)
+		if err != nil {
+			return err
+		}
+		key, _, err := it.current()
+		if err != nil {
+			return err
+		}
+		v.Estack().Push(key)
+		return nil
+	})
+	s.Register("Neo.Iterator.Keys", func(v *VM) error {
+		it, err := popIterator(v)
+		if err != nil {
+			return err
+		}
+		keys, _ := it.rest()
+		v.Estack().Push(NewInteropInterface(newIterator(keys, keys)))
+		return nil
+	})
+	s.Register("Neo.Iterator.Values", func(v *VM) error {
+		it, err := popIterator(v)
+		if err != nil {
+			return err
+		}
+		keys, values := it.rest()
+		v.Estack().Push(NewInteropInterface(newIterator(keys, values)))
+		return nil
+	})
+	s.Register("Neo.Enumerator.Concat", func(v *VM) error {
+		first, err := popIterator(v)
+		if err != nil {
+			return err
+		}
+		second, err := popIterator(v)
+		if err != nil {
+			return err
+		}
+		k1, v1 := first.rest()
+		k2, v2 := second.rest()
+		keys := append(append([]StackItem{}, k1...), k2...)
+		values := append(append([]StackItem{}, v1...), v2...)
+		v.Estack().Push(NewInteropInterface(newIterator(keys, values)))
+		return nil
+	})
+}
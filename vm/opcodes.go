@@ -0,0 +1,221 @@
+package vm
+
+import (
+	"fmt"
+)
+
+// Version is a version of the NEO virtual machine, instructions are
+// available from the version that introduced them.
+type Version int
+
+// The versions of the VM that changed the instruction set.
+const (
+	// Version20 is the instruction set of neo-vm 2.0.
+	Version20 Version = iota
+	// Version21 adds maps: NEWMAP, HASKEY, KEYS and VALUES, and VERIFY.
+	Version21
+	// Version23 adds calls with an isolated evaluation stack: CALL_I,
+	// CALL_E, CALL_ED, CALL_ET and CALL_EDT.
+	Version23
+
+	// LatestVersion is the latest version of the VM, which this package
+	// implements.
+	LatestVersion = Version23
+)
+
+var versionNames = map[Version]string{
+	Version20: "2.0",
+	Version21: "2.1",
+	Version23: "2.3",
+}
+
+func (v Version) String() string {
+	if s, ok := versionNames[v]; ok {
+		return s
+	}
+	return fmt.Sprintf("Version(%d)", int(v))
+}
+
+// ParseVersion parses a version of the VM like "2.3".
+func ParseVersion(s string) (Version, error) {
+	for v, name := range versionNames {
+		if name == s {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown VM version %s", s)
+}
+
+// OpcodeInfo describes the encoding, the price and the stack effect of an
+// instruction.
+type OpcodeInfo struct {
+	// OperandSize is the size of the operand in bytes. If PrefixedOperand
+	// is set the operand has a variable size and OperandSize is the size of
+	// the little endian length that precedes it.
+	OperandSize     int
+	PrefixedOperand bool
+
+	// Price is the price of the instruction in 0.001 GAS. The price of
+	// CHECKMULTISIG depends on the number of keys and the one of SYSCALL on
+	// the API that is called.
+	Price int64
+
+	// Pop and Push are the number of items the instruction takes from and
+	// puts on the evaluation stack, -1 if that depends on the operand or on
+	// the items on the stack.
+	Pop, Push int
+
+	// Version is the version of the VM that introduced the instruction.
+	Version Version
+}
+
+// Info returns the description of the instruction, false if the
+// instruction does not exist.
+func (op Instruction) Info() (OpcodeInfo, bool) {
+	info, ok := opcodes[op]
+	return info, ok
+}
+
+// AvailableIn reports whether the instruction exists in the given version
+// of the VM.
+func (op Instruction) AvailableIn(v Version) bool {
+	info, ok := opcodes[op]
+	return ok && info.Version <= v
+}
+
+// stackOp returns the description of an instruction without operand that
+// costs 1.
+func stackOp(pop, push int) OpcodeInfo {
+	return OpcodeInfo{Price: 1, Pop: pop, Push: push}
+}
+
+// opcodes describes every instruction of the NEO 2.x VM. Instructions of
+// other stack machines that are sometimes expected here, like 2DUP, variants
+// of UNPACK or SHA variants other than SHA1 and SHA256, are left out on
+// purpose: NEO 2.x does not have them and a script using them would FAULT.
+var opcodes = func() map[Instruction]OpcodeInfo {
+	m := map[Instruction]OpcodeInfo{
+		// Constants
+		PUSH0:     {Push: 1},
+		PUSHDATA1: {OperandSize: 1, PrefixedOperand: true, Push: 1},
+		PUSHDATA2: {OperandSize: 2, PrefixedOperand: true, Push: 1},
+		PUSHDATA4: {OperandSize: 4, PrefixedOperand: true, Push: 1},
+		PUSHM1:    {Push: 1},
+
+		// Flow control
+		NOP:      {},
+		JMP:      {OperandSize: 2, Price: 1},
+		JMPIF:    {OperandSize: 2, Price: 1, Pop: 1},
+		JMPIFNOT: {OperandSize: 2, Price: 1, Pop: 1},
+		CALL:     {OperandSize: 2, Price: 1},
+		RET:      stackOp(0, 0),
+		// A zero script hash is replaced by a hash taken from the stack.
+		APPCALL:  {OperandSize: 20, Price: 10, Pop: -1},
+		SYSCALL:  {OperandSize: 1, PrefixedOperand: true, Pop: -1, Push: -1},
+		TAILCALL: {OperandSize: 20, Price: 10, Pop: -1},
+
+		// Stack
+		DUPFROMALTSTACK: stackOp(0, 1),
+		TOALTSTACK:      stackOp(1, 0),
+		FROMALTSTACK:    stackOp(0, 1),
+		XDROP:           stackOp(2, 0),
+		XSWAP:           stackOp(1, 0),
+		XTUCK:           stackOp(1, 1),
+		DEPTH:           stackOp(0, 1),
+		DROP:            stackOp(1, 0),
+		DUP:             stackOp(1, 2),
+		NIP:             stackOp(2, 1),
+		OVER:            stackOp(2, 3),
+		PICK:            stackOp(1, 1),
+		ROLL:            stackOp(2, 1),
+		ROT:             stackOp(3, 3),
+		SWAP:            stackOp(2, 2),
+		TUCK:            stackOp(2, 3),
+
+		// Splice
+		CAT:    stackOp(2, 1),
+		SUBSTR: stackOp(3, 1),
+		LEFT:   stackOp(2, 1),
+		RIGHT:  stackOp(2, 1),
+		SIZE:   stackOp(1, 1),
+
+		// Bitwise logic
+		INVERT: stackOp(1, 1),
+		AND:    stackOp(2, 1),
+		OR:     stackOp(2, 1),
+		XOR:    stackOp(2, 1),
+		EQUAL:  stackOp(2, 1),
+
+		// Arithmetic
+		INC:         stackOp(1, 1),
+		DEC:         stackOp(1, 1),
+		SIGN:        stackOp(1, 1),
+		NEGATE:      stackOp(1, 1),
+		ABS:         stackOp(1, 1),
+		NOT:         stackOp(1, 1),
+		NZ:          stackOp(1, 1),
+		ADD:         stackOp(2, 1),
+		SUB:         stackOp(2, 1),
+		MUL:         stackOp(2, 1),
+		DIV:         stackOp(2, 1),
+		MOD:         stackOp(2, 1),
+		SHL:         stackOp(2, 1),
+		SHR:         stackOp(2, 1),
+		BOOLAND:     stackOp(2, 1),
+		BOOLOR:      stackOp(2, 1),
+		NUMEQUAL:    stackOp(2, 1),
+		NUMNOTEQUAL: stackOp(2, 1),
+		LT:          stackOp(2, 1),
+		GT:          stackOp(2, 1),
+		LTE:         stackOp(2, 1),
+		GTE:         stackOp(2, 1),
+		MIN:         stackOp(2, 1),
+		MAX:         stackOp(2, 1),
+		WITHIN:      stackOp(3, 1),
+
+		// Crypto
+		SHA1:          {Price: 10, Pop: 1, Push: 1},
+		SHA256:        {Price: 10, Pop: 1, Push: 1},
+		HASH160:       {Price: 20, Pop: 1, Push: 1},
+		HASH256:       {Price: 20, Pop: 1, Push: 1},
+		CHECKSIG:      {Price: 100, Pop: 2, Push: 1},
+		VERIFY:        {Price: 100, Pop: 3, Push: 1, Version: Version21},
+		CHECKMULTISIG: {Pop: -1, Push: 1},
+
+		// Array
+		ARRAYSIZE: stackOp(1, 1),
+		PACK:      stackOp(-1, 1),
+		UNPACK:    stackOp(1, -1),
+		PICKITEM:  stackOp(2, 1),
+		SETITEM:   stackOp(3, 0),
+		NEWARRAY:  stackOp(1, 1),
+		NEWSTRUCT: stackOp(1, 1),
+		APPEND:    stackOp(2, 0),
+		REVERSE:   stackOp(1, 0),
+		REMOVE:    stackOp(2, 0),
+		NEWMAP:    {Price: 1, Push: 1, Version: Version21},
+		HASKEY:    {Price: 1, Pop: 2, Push: 1, Version: Version21},
+		KEYS:      {Price: 1, Pop: 1, Push: 1, Version: Version21},
+		VALUES:    {Price: 1, Pop: 1, Push: 1, Version: Version21},
+
+		// Stack isolation. The operand starts with the number of return
+		// values and the number of parameters, the items that are moved
+		// between the evaluation stacks of the caller and the callee.
+		CALL_I:   {OperandSize: 4, Price: 1, Pop: -1, Push: -1, Version: Version23},
+		CALL_E:   {OperandSize: 22, Price: 10, Pop: -1, Push: -1, Version: Version23},
+		CALL_ED:  {OperandSize: 2, Price: 10, Pop: -1, Push: -1, Version: Version23},
+		CALL_ET:  {OperandSize: 22, Price: 10, Pop: -1, Push: -1, Version: Version23},
+		CALL_EDT: {OperandSize: 2, Price: 10, Pop: -1, Push: -1, Version: Version23},
+
+		// Exceptions
+		THROW:      stackOp(0, 0),
+		THROWIFNOT: stackOp(1, 0),
+	}
+	for op := PUSHBYTES1; op <= PUSHBYTES75; op++ {
+		m[op] = OpcodeInfo{OperandSize: int(op), Push: 1}
+	}
+	for op := PUSH1; op <= PUSH16; op++ {
+		m[op] = OpcodeInfo{Push: 1}
+	}
+	return m
+}()
@@ -0,0 +1,137 @@
+package vm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func runAssembly(t *testing.T, src string) *VM {
+	script, err := Assemble(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := New()
+	v.LoadScript(script)
+	if err := v.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestMapOpcodes(t *testing.T) {
+	v := runAssembly(t, `
+		NEWMAP
+		DUP
+		PUSH "a"
+		PUSH 1
+		SETITEM
+		DUP
+		PUSH "a"
+		HASKEY
+		THROWIFNOT
+		DUP
+		KEYS
+		SWAP
+		VALUES`)
+
+	values := v.Estack().Pop().Value().([]StackItem)
+	keys := v.Estack().Pop().Value().([]StackItem)
+	if len(keys) != 1 || string(keys[0].Bytes()) != "a" {
+		t.Fatalf("expected the keys [a], got %v", keys)
+	}
+	if len(values) != 1 || values[0].BigInt().Int64() != 1 {
+		t.Fatalf("expected the values [1], got %v", values)
+	}
+}
+
+// NEWARRAY on an array and NEWSTRUCT on a struct return the same item, so a
+// change through one is visible through the other. Converting between
+// arrays and structs creates a new item.
+func TestNewArrayAliasing(t *testing.T) {
+	v := runAssembly(t, `
+		PUSH 2
+		NEWARRAY
+		DUP
+		NEWARRAY
+		PUSH 0
+		PUSH 5
+		SETITEM        ; visible through the first array
+		DUP
+		NEWSTRUCT
+		PUSH 1
+		PUSH 6
+		SETITEM        ; only changes the struct
+		PUSH 1
+		NEWSTRUCT
+		DUP
+		NEWSTRUCT
+		PUSH 0
+		PUSH 7
+		SETITEM`)
+
+	s := v.Estack().Pop().Value().([]StackItem)
+	if s[0].BigInt().Int64() != 7 {
+		t.Fatalf("expected the struct to hold 7, got %s", s[0])
+	}
+	a := v.Estack().Pop().Value().([]StackItem)
+	if a[0].BigInt().Int64() != 5 || a[1].BigInt().Int64() != 0 {
+		t.Fatalf("expected the array to hold 5 and false, got %v", a)
+	}
+}
+
+func TestCallIsolated(t *testing.T) {
+	src := `
+		PUSH 7         ; not visible to the callee
+		PUSH 2
+		PUSH 3
+		CALL_I 1 2 add
+		RET
+	add:
+		DEPTH
+		PUSH 2
+		NUMEQUAL
+		THROWIFNOT
+		PUSH 99        ; not returned
+		ROT
+		ROT
+		ADD`
+	v := runAssembly(t, src)
+	items := v.Estack().Items()
+	if len(items) != 2 || items[0].BigInt().Int64() != 5 || items[1].BigInt().Int64() != 7 {
+		t.Fatalf("expected [5 7], got %v", items)
+	}
+
+	// The disassembly of the call assembles into the same script.
+	script, _ := Assemble(strings.NewReader(src))
+	instrs, err := Disassemble(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := instrs[3].String(); s != "CALL_I 1 2 9" {
+		t.Fatalf("expected CALL_I 1 2 9, got %s", s)
+	}
+	var lines []string
+	for _, instr := range instrs {
+		lines = append(lines, instr.String())
+	}
+	again, err := Assemble(strings.NewReader(strings.Join(lines, "\n")))
+	if err != nil || !bytes.Equal(again, script) {
+		t.Fatalf("expected %x, got %x (%v)", script, again, err)
+	}
+}
+
+func TestOpcodeVersions(t *testing.T) {
+	if !ADD.AvailableIn(Version20) || NEWMAP.AvailableIn(Version20) || !NEWMAP.AvailableIn(Version21) {
+		t.Fatal("wrong availability of ADD or NEWMAP")
+	}
+	if CALL_I.AvailableIn(Version21) || !CALL_I.AvailableIn(LatestVersion) {
+		t.Fatal("wrong availability of CALL_I")
+	}
+	if Instruction(0x50).AvailableIn(LatestVersion) {
+		t.Fatal("0x50 is not an instruction")
+	}
+	if v, err := ParseVersion("2.1"); err != nil || v != Version21 {
+		t.Fatalf("expected version 2.1, got %s (%v)", v, err)
+	}
+}
@@ -0,0 +1,226 @@
+package vm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Type prefixes of serialized stack items.
+const (
+	byteArrayType        byte = 0x00
+	booleanType          byte = 0x01
+	integerType          byte = 0x02
+	interopInterfaceType byte = 0x40
+	arrayType            byte = 0x80
+	structType           byte = 0x81
+	mapType              byte = 0x82
+)
+
+// SerializeItem serializes the item the way Neo.Runtime.Serialize does.
+// Interop interfaces and items that contain themselves can not be
+// serialized.
+func SerializeItem(item StackItem) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := serializeItem(&buf, item, map[StackItem]bool{}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func serializeItem(w *bytes.Buffer, item StackItem, seen map[StackItem]bool) error {
+	switch t := item.(type) {
+	case *ByteArray:
+		w.WriteByte(byteArrayType)
+		writeVarBytes(w, t.value)
+	case *Boolean:
+		w.WriteByte(booleanType)
+		if t.value {
+			w.WriteByte(1)
+		} else {
+			w.WriteByte(0)
+		}
+	case *Integer:
+		w.WriteByte(integerType)
+		writeVarBytes(w, t.Bytes())
+	case *Array, *Struct:
+		if seen[item] {
+			return errors.New("can not serialize an item that contains itself")
+		}
+		seen[item] = true
+		items, _ := arrayItems(item)
+		if _, ok := item.(*Struct); ok {
+			w.WriteByte(structType)
+		} else {
+			w.WriteByte(arrayType)
+		}
+		writeVarInt(w, uint64(len(items)))
+		for _, it := range items {
+			if err := serializeItem(w, it, seen); err != nil {
+				return err
+			}
+		}
+		delete(seen, item)
+	case *Map:
+		if seen[item] {
+			return errors.New("can not serialize an item that contains itself")
+		}
+		seen[item] = true
+		w.WriteByte(mapType)
+		writeVarInt(w, uint64(t.Len()))
+		for _, e := range t.value {
+			if err := serializeItem(w, e.Key, seen); err != nil {
+				return err
+			}
+			if err := serializeItem(w, e.Value, seen); err != nil {
+				return err
+			}
+		}
+		delete(seen, item)
+	default:
+		return fmt.Errorf("can not serialize %s", item)
+	}
+	return nil
+}
+
+// DeserializeItem deserializes an item that was serialized with
+// SerializeItem.
+func DeserializeItem(data []byte) (StackItem, error) {
+	r := bytes.NewReader(data)
+	item, err := deserializeItem(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		return nil, errors.New("unexpected data after the serialized item")
+	}
+	return item, nil
+}
+
+func deserializeItem(r *bytes.Reader) (StackItem, error) {
+	typ, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case byteArrayType:
+		b, err := readVarBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		return NewByteArray(b), nil
+	case booleanType:
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		return NewBoolean(b != 0), nil
+	case integerType:
+		b, err := readVarBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		return NewInteger(bytesToBigInt(b)), nil
+	case arrayType, structType:
+		n, err := readVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(r.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		items := make([]StackItem, n)
+		for i := range items {
+			if items[i], err = deserializeItem(r); err != nil {
+				return nil, err
+			}
+		}
+		if typ == structType {
+			return NewStruct(items), nil
+		}
+		return NewArray(items), nil
+	case mapType:
+		n, err := readVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		m := NewMap()
+		for i := uint64(0); i < n; i++ {
+			key, err := deserializeItem(r)
+			if err != nil {
+				return nil, err
+			}
+			value, err := deserializeItem(r)
+			if err != nil {
+				return nil, err
+			}
+			m.Set(key, value)
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unknown item type 0x%02x", typ)
+	}
+}
+
+func writeVarInt(w *bytes.Buffer, n uint64) {
+	b := make([]byte, 8)
+	switch {
+	case n < 0xfd:
+		w.WriteByte(byte(n))
+	case n <= 0xffff:
+		w.WriteByte(0xfd)
+		binary.LittleEndian.PutUint16(b, uint16(n))
+		w.Write(b[:2])
+	case n <= 0xffffffff:
+		w.WriteByte(0xfe)
+		binary.LittleEndian.PutUint32(b, uint32(n))
+		w.Write(b[:4])
+	default:
+		w.WriteByte(0xff)
+		binary.LittleEndian.PutUint64(b, n)
+		w.Write(b)
+	}
+}
+
+func writeVarBytes(w *bytes.Buffer, b []byte) {
+	writeVarInt(w, uint64(len(b)))
+	w.Write(b)
+}
+
+func readVarInt(r *bytes.Reader) (uint64, error) {
+	prefix, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	size := 0
+	switch prefix {
+	case 0xfd:
+		size = 2
+	case 0xfe:
+		size = 4
+	case 0xff:
+		size = 8
+	default:
+		return uint64(prefix), nil
+	}
+	b := make([]byte, 8)
+	if _, err := io.ReadFull(r, b[:size]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+func readVarBytes(r *bytes.Reader) ([]byte, error) {
+	n, err := readVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(r, b)
+	return b, err
+}
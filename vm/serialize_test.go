@@ -0,0 +1,50 @@
+package vm
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSerializeItem(t *testing.T) {
+	m := NewMap()
+	m.Set(NewByteArray([]byte("key")), NewBoolean(true))
+	item := NewArray([]StackItem{
+		NewIntegerInt64(-1),
+		NewByteArray([]byte("a")),
+		NewStruct([]StackItem{NewIntegerInt64(0)}),
+		m,
+	})
+
+	b, err := SerializeItem(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []byte{
+		0x80, 0x04,
+		0x02, 0x01, 0xff,
+		0x00, 0x01, 'a',
+		0x81, 0x01, 0x02, 0x00,
+		0x82, 0x01, 0x00, 0x03, 'k', 'e', 'y', 0x01, 0x01,
+	}
+	if !bytes.Equal(b, expect) {
+		t.Fatalf("expected %x, got %x", expect, b)
+	}
+
+	res, err := DeserializeItem(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := res.(*Array).value
+	if len(items) != 4 || !items[0].Equals(NewIntegerInt64(-1)) || !items[2].Equals(item.value[2]) {
+		t.Fatalf("expected %s, got %s", item, res)
+	}
+	if value, _ := items[3].(*Map).Get(NewByteArray([]byte("key"))); !value.Bool() {
+		t.Fatalf("expected the map to be deserialized, got %s", items[3])
+	}
+
+	// Items that contain themselves can not be serialized.
+	item.value[0] = item
+	if _, err := SerializeItem(item); err == nil {
+		t.Fatal("expected an error")
+	}
+}
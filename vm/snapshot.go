@@ -0,0 +1,613 @@
+package vm
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/CityOfZion/neo-go/pkg/util"
+)
+
+// Snapshot is the state of a VM and of the environment it executes against
+// at some point of a scenario. A snapshot can be restored any number of
+// times and saved to a file, restoring it continues the execution exactly
+// where it was taken.
+type Snapshot struct {
+	VM  *vmSnapshot
+	Env *envSnapshot
+
+	// Items holds the stack items of the VM and of the notifications,
+	// items refer to each other by their index.
+	Items []snapshotItem
+}
+
+type vmSnapshot struct {
+	State       State
+	Error       string
+	GasConsumed util.Fixed8
+	GasLimit    util.Fixed8
+	Limits      Limits
+	Message     []byte
+
+	// Stacks are the evaluation stacks, bottom first. The first one is
+	// the result stack.
+	Stacks   [][]int
+	AltStack []int
+	Contexts []snapshotContext
+}
+
+type snapshotContext struct {
+	Script  []byte
+	IP      int
+	RVCount int
+	Stack   int
+}
+
+type envSnapshot struct {
+	Trigger       byte
+	Time          uint32
+	Witnesses     []util.Uint160
+	Container     *Transaction
+	Storage       map[util.Uint160]map[string]StorageItem
+	Chain         *Blockchain
+	Logs          []string
+	Notifications []snapshotNotification
+}
+
+type snapshotNotification struct {
+	ScriptHash util.Uint160
+	Item       int
+}
+
+// snapshotItem is a stack item. Value holds the bytes of a byte array,
+// integer or boolean, Items the items of an array or struct and the keys
+// and values of a map, one after the other.
+type snapshotItem struct {
+	Type    byte
+	Value   []byte
+	Items   []int
+	Interop *snapshotInterop
+}
+
+// snapshotInterop is the value of an interop interface. Values of the
+// blockchain are referred to by their position in it.
+type snapshotInterop struct {
+	Kind       string
+	ScriptHash util.Uint160
+	ReadOnly   bool
+	Hash       util.Uint256
+	Index      int
+	Keys       []int
+	Values     []int
+}
+
+// TakeSnapshot returns a snapshot of the VM and of the environment, either
+// may be nil to leave it out. Interop interfaces are only supported for the
+// values of the environment syscalls.
+func TakeSnapshot(v *VM, e *Environment) (*Snapshot, error) {
+	w := &snapshotWriter{env: e, ids: make(map[StackItem]int)}
+	s := new(Snapshot)
+
+	if e != nil {
+		env := &envSnapshot{
+			Trigger:   e.Trigger,
+			Time:      e.Time,
+			Witnesses: append([]util.Uint160{}, e.Witnesses...),
+			Storage:   make(map[util.Uint160]map[string]StorageItem, len(e.Storage)),
+			Chain:     e.Chain.clone(),
+			Logs:      append([]string{}, e.Logs...),
+		}
+		if e.Container != nil {
+			env.Container = e.Container.clone()
+		}
+		for hash, items := range e.Storage {
+			storage := make(map[string]StorageItem, len(items))
+			for key, item := range items {
+				storage[key] = *item
+			}
+			env.Storage[hash] = storage
+		}
+		for _, n := range e.Notifications {
+			id, err := w.item(n.Item)
+			if err != nil {
+				return nil, err
+			}
+			env.Notifications = append(env.Notifications, snapshotNotification{ScriptHash: n.ScriptHash, Item: id})
+		}
+		s.Env = env
+	}
+
+	if v != nil {
+		vs := &vmSnapshot{
+			State:       v.state,
+			GasConsumed: v.gasConsumed,
+			GasLimit:    v.gasLimit,
+			Limits:      v.limits,
+			Message:     v.message,
+		}
+		if v.err != nil {
+			vs.Error = v.err.Error()
+		}
+		stacks := map[*Stack]int{}
+		addStack := func(stack *Stack) (int, error) {
+			if id, ok := stacks[stack]; ok {
+				return id, nil
+			}
+			ids, err := w.items(stack.items)
+			if err != nil {
+				return 0, err
+			}
+			stacks[stack] = len(vs.Stacks)
+			vs.Stacks = append(vs.Stacks, ids)
+			return stacks[stack], nil
+		}
+		if _, err := addStack(v.rstack); err != nil {
+			return nil, err
+		}
+		for _, ctx := range v.istack {
+			id, err := addStack(ctx.estack)
+			if err != nil {
+				return nil, err
+			}
+			vs.Contexts = append(vs.Contexts, snapshotContext{
+				Script:  ctx.script,
+				IP:      ctx.ip,
+				RVCount: ctx.rvcount,
+				Stack:   id,
+			})
+		}
+		ids, err := w.items(v.astack.items)
+		if err != nil {
+			return nil, err
+		}
+		vs.AltStack = ids
+		s.VM = vs
+	}
+
+	s.Items = w.snapshot
+	return s, nil
+}
+
+// Restore restores the snapshot onto the VM and the environment, either may
+// be nil to leave it out. They are changed in place, so the syscalls and
+// script getter registered on the VM keep working.
+func (s *Snapshot) Restore(v *VM, e *Environment) error {
+	if err := s.check(); err != nil {
+		return err
+	}
+	r := &snapshotReader{env: e, snapshot: s.Items}
+
+	if e != nil && s.Env != nil {
+		env := s.Env
+		chain := env.Chain.clone()
+		if e.Chain == nil {
+			e.Chain = chain
+		} else {
+			*e.Chain = *chain
+		}
+		e.Trigger = env.Trigger
+		e.Time = env.Time
+		e.Witnesses = append([]util.Uint160{}, env.Witnesses...)
+		e.Container = nil
+		if env.Container != nil {
+			e.Container = e.Chain.GetTransaction(env.Container.Hash)
+			if e.Container == nil {
+				e.Container = env.Container.clone()
+			}
+		}
+		e.Storage = make(map[util.Uint160]map[string]*StorageItem, len(env.Storage))
+		for hash, items := range env.Storage {
+			storage := make(map[string]*StorageItem, len(items))
+			for key, item := range items {
+				item := item
+				storage[key] = &item
+			}
+			e.Storage[hash] = storage
+		}
+		e.Logs = append([]string{}, env.Logs...)
+	}
+
+	// The items are restored after the blockchain, as interop interfaces
+	// refer to its values.
+	if err := r.restoreItems(); err != nil {
+		return err
+	}
+
+	if e != nil && s.Env != nil {
+		e.Notifications = nil
+		for _, n := range s.Env.Notifications {
+			e.Notifications = append(e.Notifications, Notification{ScriptHash: n.ScriptHash, Item: r.items[n.Item]})
+		}
+	}
+
+	if v != nil && s.VM != nil {
+		vs := s.VM
+		if len(vs.Stacks) == 0 {
+			return errors.New("snapshot has no result stack")
+		}
+		stacks := make([]*Stack, len(vs.Stacks))
+		for i, ids := range vs.Stacks {
+			stacks[i] = &Stack{items: r.list(ids)}
+		}
+		v.istack = nil
+		for _, c := range vs.Contexts {
+			if c.Stack < 0 || c.Stack >= len(stacks) {
+				return fmt.Errorf("snapshot has no stack %d", c.Stack)
+			}
+			ctx := NewContext(c.Script)
+			ctx.ip = c.IP
+			ctx.rvcount = c.RVCount
+			ctx.estack = stacks[c.Stack]
+			v.istack = append(v.istack, ctx)
+		}
+		v.rstack = stacks[0]
+		v.estack = v.rstack
+		if ctx := v.Context(); ctx != nil {
+			v.estack = ctx.estack
+		}
+		v.astack = &Stack{items: r.list(vs.AltStack)}
+		v.state = vs.State
+		v.err = nil
+		if vs.Error != "" {
+			v.err = errors.New(vs.Error)
+		}
+		v.gasConsumed = vs.GasConsumed
+		v.gasLimit = vs.GasLimit
+		v.limits = vs.Limits
+		v.message = vs.Message
+	}
+	return nil
+}
+
+// check checks that the items the snapshot refers to are in it.
+func (s *Snapshot) check() error {
+	lists := [][]int{}
+	for _, item := range s.Items {
+		lists = append(lists, item.Items)
+		if item.Interop != nil {
+			lists = append(lists, item.Interop.Keys, item.Interop.Values)
+		}
+	}
+	if s.VM != nil {
+		lists = append(append(lists, s.VM.Stacks...), s.VM.AltStack)
+	}
+	if s.Env != nil {
+		for _, n := range s.Env.Notifications {
+			lists = append(lists, []int{n.Item})
+		}
+	}
+	for _, ids := range lists {
+		for _, id := range ids {
+			if id < 0 || id >= len(s.Items) {
+				return fmt.Errorf("snapshot has no item %d", id)
+			}
+		}
+	}
+	return nil
+}
+
+// Write writes the snapshot to w in the gob format.
+func (s *Snapshot) Write(w io.Writer) error {
+	return gob.NewEncoder(w).Encode(s)
+}
+
+// Save writes the snapshot to the file at path.
+func (s *Snapshot) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := s.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadSnapshot reads a snapshot that was written with Snapshot.Write.
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	s := new(Snapshot)
+	if err := gob.NewDecoder(r).Decode(s); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %v", err)
+	}
+	return s, nil
+}
+
+// LoadSnapshot reads the snapshot in the file at path.
+func LoadSnapshot(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadSnapshot(f)
+}
+
+// snapshotWriter adds the stack items to a snapshot. Items that are held in
+// several places, like arrays, are added once.
+type snapshotWriter struct {
+	env      *Environment
+	ids      map[StackItem]int
+	snapshot []snapshotItem
+}
+
+func (w *snapshotWriter) items(items []StackItem) ([]int, error) {
+	ids := make([]int, len(items))
+	for i, item := range items {
+		id, err := w.item(item)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+func (w *snapshotWriter) item(item StackItem) (int, error) {
+	if id, ok := w.ids[item]; ok {
+		return id, nil
+	}
+	// The id is known before the items it holds are added, so items can
+	// contain themselves.
+	id := len(w.snapshot)
+	w.ids[item] = id
+	w.snapshot = append(w.snapshot, snapshotItem{})
+
+	var (
+		s   snapshotItem
+		err error
+	)
+	switch t := item.(type) {
+	case *ByteArray:
+		s = snapshotItem{Type: byteArrayType, Value: t.value}
+	case *Boolean:
+		s = snapshotItem{Type: booleanType, Value: t.Bytes()}
+	case *Integer:
+		s = snapshotItem{Type: integerType, Value: t.Bytes()}
+	case *Array:
+		s.Type = arrayType
+		s.Items, err = w.items(t.value)
+	case *Struct:
+		s.Type = structType
+		s.Items, err = w.items(t.value)
+	case *Map:
+		s.Type = mapType
+		for _, e := range t.value {
+			var key, value int
+			if key, err = w.item(e.Key); err != nil {
+				break
+			}
+			if value, err = w.item(e.Value); err != nil {
+				break
+			}
+			s.Items = append(s.Items, key, value)
+		}
+	case *InteropInterface:
+		s.Type = interopInterfaceType
+		s.Interop, err = w.interop(t.value)
+	default:
+		err = fmt.Errorf("can not snapshot item %s", item)
+	}
+	if err != nil {
+		return 0, err
+	}
+	w.snapshot[id] = s
+	return id, nil
+}
+
+// interop returns the snapshot of the value of an interop interface.
+func (w *snapshotWriter) interop(value interface{}) (*snapshotInterop, error) {
+	switch t := value.(type) {
+	case *StorageContext:
+		return &snapshotInterop{Kind: "storage", ScriptHash: t.ScriptHash, ReadOnly: t.ReadOnly}, nil
+	case *iterator:
+		keys, err := w.items(t.keys)
+		if err != nil {
+			return nil, err
+		}
+		values, err := w.items(t.values)
+		if err != nil {
+			return nil, err
+		}
+		return &snapshotInterop{Kind: "iterator", Keys: keys, Values: values, Index: t.index}, nil
+	}
+
+	if w.env != nil {
+		for i, b := range w.env.Chain.Blocks {
+			if value == b {
+				return &snapshotInterop{Kind: "block", Index: i}, nil
+			}
+			if value == &b.Header {
+				return &snapshotInterop{Kind: "header", Index: i}, nil
+			}
+		}
+		for hash, contract := range w.env.Chain.Contracts {
+			if value == contract {
+				return &snapshotInterop{Kind: "contract", ScriptHash: hash}, nil
+			}
+		}
+		for _, tx := range w.env.transactions() {
+			if value == tx {
+				return &snapshotInterop{Kind: "transaction", Hash: tx.Hash}, nil
+			}
+			for i, attr := range tx.Attributes {
+				if value == attr {
+					return &snapshotInterop{Kind: "attribute", Hash: tx.Hash, Index: i}, nil
+				}
+			}
+			for i, in := range tx.Inputs {
+				if value == in {
+					return &snapshotInterop{Kind: "input", Hash: tx.Hash, Index: i}, nil
+				}
+			}
+			for i, out := range tx.Outputs {
+				if value == out {
+					return &snapshotInterop{Kind: "output", Hash: tx.Hash, Index: i}, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("can not snapshot interop interface of type %T", value)
+}
+
+// snapshotReader restores the stack items of a snapshot.
+type snapshotReader struct {
+	env      *Environment
+	snapshot []snapshotItem
+	items    []StackItem
+}
+
+func (r *snapshotReader) list(ids []int) []StackItem {
+	items := make([]StackItem, len(ids))
+	for i, id := range ids {
+		items[i] = r.items[id]
+	}
+	return items
+}
+
+// restoreItems creates the items first and fills in the items they hold
+// after, as items can refer to items that come after them.
+func (r *snapshotReader) restoreItems() error {
+	r.items = make([]StackItem, len(r.snapshot))
+	for i, s := range r.snapshot {
+		switch s.Type {
+		case byteArrayType:
+			r.items[i] = NewByteArray(s.Value)
+		case booleanType:
+			r.items[i] = NewBoolean(len(s.Value) > 0 && s.Value[0] != 0)
+		case integerType:
+			r.items[i] = NewInteger(bytesToBigInt(s.Value))
+		case arrayType:
+			r.items[i] = NewArray(nil)
+		case structType:
+			r.items[i] = NewStruct(nil)
+		case mapType:
+			r.items[i] = NewMap()
+		case interopInterfaceType:
+			r.items[i] = NewInteropInterface(nil)
+		default:
+			return fmt.Errorf("invalid item type %d", s.Type)
+		}
+	}
+	for i, s := range r.snapshot {
+		switch t := r.items[i].(type) {
+		case *Array:
+			t.value = r.list(s.Items)
+		case *Struct:
+			t.value = r.list(s.Items)
+		case *Map:
+			for j := 0; j+1 < len(s.Items); j += 2 {
+				t.value = append(t.value, MapElement{Key: r.items[s.Items[j]], Value: r.items[s.Items[j+1]]})
+			}
+		case *InteropInterface:
+			if s.Interop == nil {
+				return fmt.Errorf("interop interface %d has no value", i)
+			}
+			value, err := r.interop(s.Interop)
+			if err != nil {
+				return err
+			}
+			t.value = value
+		}
+	}
+	return nil
+}
+
+func (r *snapshotReader) interop(s *snapshotInterop) (interface{}, error) {
+	switch s.Kind {
+	case "storage":
+		return &StorageContext{ScriptHash: s.ScriptHash, ReadOnly: s.ReadOnly}, nil
+	case "iterator":
+		return &iterator{keys: r.list(s.Keys), values: r.list(s.Values), index: s.Index}, nil
+	}
+	if r.env == nil {
+		return nil, fmt.Errorf("can not restore interop interface %s without an environment", s.Kind)
+	}
+
+	chain := r.env.Chain
+	switch s.Kind {
+	case "block", "header":
+		if s.Index < 0 || s.Index >= len(chain.Blocks) {
+			break
+		}
+		if s.Kind == "header" {
+			return &chain.Blocks[s.Index].Header, nil
+		}
+		return chain.Blocks[s.Index], nil
+	case "contract":
+		if contract, ok := chain.Contracts[s.ScriptHash]; ok {
+			return contract, nil
+		}
+	case "transaction", "attribute", "input", "output":
+		var tx *Transaction
+		for _, t := range r.env.transactions() {
+			if t.Hash == s.Hash {
+				tx = t
+				break
+			}
+		}
+		switch {
+		case tx == nil:
+		case s.Kind == "transaction":
+			return tx, nil
+		case s.Kind == "attribute" && s.Index < len(tx.Attributes):
+			return tx.Attributes[s.Index], nil
+		case s.Kind == "input" && s.Index < len(tx.Inputs):
+			return tx.Inputs[s.Index], nil
+		case s.Kind == "output" && s.Index < len(tx.Outputs):
+			return tx.Outputs[s.Index], nil
+		}
+	default:
+		return nil, fmt.Errorf("invalid interop interface %s", s.Kind)
+	}
+	return nil, fmt.Errorf("%s of interop interface not found in the blockchain", s.Kind)
+}
+
+// transactions returns the transactions of the blockchain and the container.
+func (e *Environment) transactions() []*Transaction {
+	var txs []*Transaction
+	for _, b := range e.Chain.Blocks {
+		txs = append(txs, b.Transactions...)
+	}
+	if e.Container != nil {
+		txs = append(txs, e.Container)
+	}
+	return txs
+}
+
+// clone returns a copy of the blockchain that does not share blocks,
+// transactions or contracts with it.
+func (c *Blockchain) clone() *Blockchain {
+	clone := &Blockchain{Contracts: make(map[util.Uint160]*Contract, len(c.Contracts))}
+	for _, b := range c.Blocks {
+		block := &Block{Header: b.Header}
+		for _, tx := range b.Transactions {
+			block.Transactions = append(block.Transactions, tx.clone())
+		}
+		clone.Blocks = append(clone.Blocks, block)
+	}
+	for hash, contract := range c.Contracts {
+		c := *contract
+		clone.Contracts[hash] = &c
+	}
+	return clone
+}
+
+func (tx *Transaction) clone() *Transaction {
+	clone := &Transaction{Hash: tx.Hash, Type: tx.Type}
+	for _, attr := range tx.Attributes {
+		a := *attr
+		clone.Attributes = append(clone.Attributes, &a)
+	}
+	for _, in := range tx.Inputs {
+		i := *in
+		clone.Inputs = append(clone.Inputs, &i)
+	}
+	for _, out := range tx.Outputs {
+		o := *out
+		clone.Outputs = append(clone.Outputs, &o)
+	}
+	return clone
+}
@@ -0,0 +1,100 @@
+package vm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	script, err := Assemble(strings.NewReader(`
+		NEWMAP
+		DUP
+		TOALTSTACK
+		DUPFROMALTSTACK
+		SYSCALL "Neo.Runtime.Notify"   ; the notification holds the map too
+		PUSH "a"
+		PUSH 2
+		CALL_I 1 1 double
+		SETITEM                        ; changes the map of the alt stack and the notification
+		FROMALTSTACK
+		PUSH "a"
+		PICKITEM
+		PUSH "count"
+		SYSCALL "Neo.Storage.GetContext"
+		SYSCALL "Neo.Storage.Put"
+		RET
+	double:
+		SYSCALL "Neo.Storage.GetContext"
+		SWAP
+		DUP
+		ROT
+		PUSH "x"
+		SWAP
+		SYSCALL "Neo.Storage.Put"
+		DUP
+		ADD`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := NewEnvironment()
+	hash := env.Chain.AddContract(script, true, false)
+	v := New()
+	env.Attach(v)
+	v.LoadScript(script)
+	// Stop in the isolated call, with a storage context on its stack.
+	for i := 0; i < 9; i++ {
+		v.Step()
+	}
+	if v.Depth() != 2 {
+		t.Fatalf("expected a depth of 2, got %d", v.Depth())
+	}
+	snapshot, err := TakeSnapshot(v, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	if err := snapshot.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err = ReadSnapshot(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := NewEnvironment()
+	rv := New()
+	restored.Attach(rv)
+	// A snapshot can be restored again after the scenario continued.
+	for i := 0; i < 2; i++ {
+		if err := snapshot.Restore(rv, restored); err != nil {
+			t.Fatal(err)
+		}
+		if len(restored.Storage[hash]) != 0 || restored.Chain.GetScript(hash) == nil {
+			t.Fatal("expected the storage and blockchain of the snapshot")
+		}
+		if err := rv.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if x, count := restored.Get(hash, []byte("x")), restored.Get(hash, []byte("count")); !bytes.Equal(x, []byte{2}) || !bytes.Equal(count, []byte{4}) {
+			t.Fatalf("expected x = 2 and count = 4, got %x and %x", x, count)
+		}
+		if rv.GasConsumed() != v.GasConsumed() {
+			t.Fatalf("expected %s GAS, got %s", v.GasConsumed(), rv.GasConsumed())
+		}
+		m := restored.Notifications[0].Item.(*Map)
+		if a, _ := m.Get(NewByteArray([]byte("a"))); a == nil || a.BigInt().Int64() != 4 {
+			t.Fatalf("expected the notified map to hold a = 4, got %s", m)
+		}
+	}
+
+	// Values that the environment did not create can not be snapshot.
+	v.Estack().Push(NewInteropInterface("value"))
+	if _, err := TakeSnapshot(v, env); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,78 @@
+package vm
+
+// Stack is a stack of items, the top of the stack is at index 0.
+type Stack struct {
+	items []StackItem
+}
+
+// NewStack returns an empty stack.
+func NewStack() *Stack {
+	return &Stack{}
+}
+
+// Len returns the number of items on the stack.
+func (s *Stack) Len() int {
+	return len(s.items)
+}
+
+// Push puts the item on top of the stack.
+func (s *Stack) Push(item StackItem) {
+	s.items = append(s.items, item)
+}
+
+// Pop removes the item on top of the stack and returns it.
+func (s *Stack) Pop() StackItem {
+	return s.Remove(0)
+}
+
+// Peek returns the n-th item from the top of the stack.
+func (s *Stack) Peek(n int) StackItem {
+	return s.items[s.index(n)]
+}
+
+// Insert puts the item at the n-th position from the top of the stack, n
+// may be the length of the stack to put it at the bottom.
+func (s *Stack) Insert(n int, item StackItem) {
+	if n < 0 || n > len(s.items) {
+		panic("stack index out of range")
+	}
+	i := len(s.items) - n
+	s.items = append(s.items, nil)
+	copy(s.items[i+1:], s.items[i:])
+	s.items[i] = item
+}
+
+// Remove removes the n-th item from the top of the stack and returns it.
+func (s *Stack) Remove(n int) StackItem {
+	i := s.index(n)
+	item := s.items[i]
+	s.items = append(s.items[:i], s.items[i+1:]...)
+	return item
+}
+
+// Swap swaps the n-th and m-th item from the top of the stack.
+func (s *Stack) Swap(n, m int) {
+	i, j := s.index(n), s.index(m)
+	s.items[i], s.items[j] = s.items[j], s.items[i]
+}
+
+// Items returns the items on the stack, starting with the top.
+func (s *Stack) Items() []StackItem {
+	items := make([]StackItem, len(s.items))
+	for i := range s.items {
+		items[i] = s.items[len(s.items)-1-i]
+	}
+	return items
+}
+
+// Clear removes all items from the stack.
+func (s *Stack) Clear() {
+	s.items = nil
+}
+
+func (s *Stack) index(n int) int {
+	if n < 0 || n >= len(s.items) {
+		panic("stack index out of range")
+	}
+	return len(s.items) - 1 - n
+}
@@ -0,0 +1,458 @@
+package vm
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"reflect"
+)
+
+// MaxSizeForBigInteger is the maximum size in bytes of an integer.
+const MaxSizeForBigInteger = 32
+
+// StackItem is an item on the evaluation or alt stack of the VM. Items are
+// converted implicitly when an instruction needs an other type, conversions
+// that are not supported make the VM fault.
+type StackItem interface {
+	fmt.Stringer
+
+	// Value returns the underlying Go value of the item.
+	Value() interface{}
+	// BigInt converts the item to an integer.
+	BigInt() *big.Int
+	// Bytes converts the item to a byte array.
+	Bytes() []byte
+	// Bool converts the item to a boolean.
+	Bool() bool
+	// Equals reports whether the item is equal to the other one, as EQUAL
+	// compares them.
+	Equals(other StackItem) bool
+}
+
+// Integer is an arbitrary size integer.
+type Integer struct {
+	value *big.Int
+}
+
+// NewInteger returns a new Integer holding n.
+func NewInteger(n *big.Int) *Integer {
+	return &Integer{value: n}
+}
+
+// NewIntegerInt64 returns a new Integer holding n.
+func NewIntegerInt64(n int64) *Integer {
+	return &Integer{value: big.NewInt(n)}
+}
+
+// Value implements the StackItem interface.
+func (i *Integer) Value() interface{} { return i.value }
+
+// BigInt implements the StackItem interface.
+func (i *Integer) BigInt() *big.Int { return i.value }
+
+// Bytes implements the StackItem interface. Integers are encoded little
+// endian in two's complement, zero is an empty byte array.
+func (i *Integer) Bytes() []byte { return bigIntToBytes(i.value) }
+
+// Bool implements the StackItem interface.
+func (i *Integer) Bool() bool { return i.value.Sign() != 0 }
+
+// Equals implements the StackItem interface.
+func (i *Integer) Equals(other StackItem) bool {
+	if o, ok := other.(*Integer); ok {
+		return i.value.Cmp(o.value) == 0
+	}
+	return bytesEqual(i, other)
+}
+
+func (i *Integer) String() string { return i.value.String() }
+
+// ByteArray is an array of bytes.
+type ByteArray struct {
+	value []byte
+}
+
+// NewByteArray returns a new ByteArray holding b.
+func NewByteArray(b []byte) *ByteArray {
+	return &ByteArray{value: b}
+}
+
+// Value implements the StackItem interface.
+func (b *ByteArray) Value() interface{} { return b.value }
+
+// BigInt implements the StackItem interface.
+func (b *ByteArray) BigInt() *big.Int { return bytesToBigInt(b.value) }
+
+// Bytes implements the StackItem interface.
+func (b *ByteArray) Bytes() []byte { return b.value }
+
+// Bool implements the StackItem interface. A byte array is true if any of
+// its bytes is not zero, byte arrays longer than an integer are always true.
+func (b *ByteArray) Bool() bool {
+	if len(b.value) > MaxSizeForBigInteger {
+		return true
+	}
+	for _, c := range b.value {
+		if c != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Equals implements the StackItem interface.
+func (b *ByteArray) Equals(other StackItem) bool { return bytesEqual(b, other) }
+
+func (b *ByteArray) String() string { return fmt.Sprintf("%x", b.value) }
+
+// Boolean is true or false.
+type Boolean struct {
+	value bool
+}
+
+// NewBoolean returns a new Boolean holding b.
+func NewBoolean(b bool) *Boolean {
+	return &Boolean{value: b}
+}
+
+// Value implements the StackItem interface.
+func (b *Boolean) Value() interface{} { return b.value }
+
+// BigInt implements the StackItem interface.
+func (b *Boolean) BigInt() *big.Int {
+	if b.value {
+		return big.NewInt(1)
+	}
+	return big.NewInt(0)
+}
+
+// Bytes implements the StackItem interface. True is encoded as 1 and false as
+// an empty byte array.
+func (b *Boolean) Bytes() []byte {
+	if b.value {
+		return []byte{1}
+	}
+	return []byte{}
+}
+
+// Bool implements the StackItem interface.
+func (b *Boolean) Bool() bool { return b.value }
+
+// Equals implements the StackItem interface.
+func (b *Boolean) Equals(other StackItem) bool {
+	if o, ok := other.(*Boolean); ok {
+		return b.value == o.value
+	}
+	return bytesEqual(b, other)
+}
+
+func (b *Boolean) String() string { return fmt.Sprint(b.value) }
+
+// Array is an array of stack items. Arrays are references, changing the
+// items changes them for every holder of the array.
+type Array struct {
+	value []StackItem
+}
+
+// NewArray returns a new Array holding items.
+func NewArray(items []StackItem) *Array {
+	return &Array{value: items}
+}
+
+// Value implements the StackItem interface.
+func (a *Array) Value() interface{} { return a.value }
+
+// BigInt implements the StackItem interface.
+func (a *Array) BigInt() *big.Int { panic("array can not be converted to an integer") }
+
+// Bytes implements the StackItem interface.
+func (a *Array) Bytes() []byte { panic("array can not be converted to a byte array") }
+
+// Bool implements the StackItem interface.
+func (a *Array) Bool() bool { return true }
+
+// Equals implements the StackItem interface.
+func (a *Array) Equals(other StackItem) bool { return StackItem(a) == other }
+
+func (a *Array) String() string { return fmt.Sprint(a.value) }
+
+// Struct is an array of stack items that the compiler uses for structs.
+// Structs are values, they are cloned when they are stored in an array,
+// struct or map and two structs are equal if their items are equal.
+type Struct struct {
+	value []StackItem
+}
+
+// NewStruct returns a new Struct holding items.
+func NewStruct(items []StackItem) *Struct {
+	return &Struct{value: items}
+}
+
+// Value implements the StackItem interface.
+func (s *Struct) Value() interface{} { return s.value }
+
+// BigInt implements the StackItem interface.
+func (s *Struct) BigInt() *big.Int { panic("struct can not be converted to an integer") }
+
+// Bytes implements the StackItem interface.
+func (s *Struct) Bytes() []byte { panic("struct can not be converted to a byte array") }
+
+// Bool implements the StackItem interface.
+func (s *Struct) Bool() bool { return true }
+
+// Equals implements the StackItem interface.
+func (s *Struct) Equals(other StackItem) bool {
+	o, ok := other.(*Struct)
+	if !ok || len(o.value) != len(s.value) {
+		return false
+	}
+	for i := range s.value {
+		if !s.value[i].Equals(o.value[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Clone returns a copy of the struct, structs inside it are cloned as well.
+func (s *Struct) Clone() *Struct {
+	items := make([]StackItem, len(s.value))
+	for i, item := range s.value {
+		if st, ok := item.(*Struct); ok {
+			item = st.Clone()
+		}
+		items[i] = item
+	}
+	return NewStruct(items)
+}
+
+func (s *Struct) String() string { return fmt.Sprint(s.value) }
+
+// MapElement is a key value pair of a Map.
+type MapElement struct {
+	Key   StackItem
+	Value StackItem
+}
+
+// Map is a map of stack items. Like arrays maps are references. Keys are
+// compared with Equals and can not be arrays, structs or maps.
+type Map struct {
+	value []MapElement
+}
+
+// NewMap returns an empty Map.
+func NewMap() *Map {
+	return &Map{}
+}
+
+// Value implements the StackItem interface, it returns the elements of the
+// map in the order they were added.
+func (m *Map) Value() interface{} { return m.value }
+
+// BigInt implements the StackItem interface.
+func (m *Map) BigInt() *big.Int { panic("map can not be converted to an integer") }
+
+// Bytes implements the StackItem interface.
+func (m *Map) Bytes() []byte { panic("map can not be converted to a byte array") }
+
+// Bool implements the StackItem interface.
+func (m *Map) Bool() bool { return true }
+
+// Equals implements the StackItem interface.
+func (m *Map) Equals(other StackItem) bool { return StackItem(m) == other }
+
+func (m *Map) String() string { return fmt.Sprint(m.value) }
+
+// Len returns the number of elements in the map.
+func (m *Map) Len() int {
+	return len(m.value)
+}
+
+// Get returns the value stored at key.
+func (m *Map) Get(key StackItem) (StackItem, bool) {
+	if i := m.index(key); i >= 0 {
+		return m.value[i].Value, true
+	}
+	return nil, false
+}
+
+// Set stores the value at key.
+func (m *Map) Set(key, value StackItem) {
+	if i := m.index(key); i >= 0 {
+		m.value[i].Value = value
+		return
+	}
+	m.value = append(m.value, MapElement{Key: key, Value: value})
+}
+
+// Delete removes key from the map.
+func (m *Map) Delete(key StackItem) {
+	if i := m.index(key); i >= 0 {
+		m.value = append(m.value[:i], m.value[i+1:]...)
+	}
+}
+
+// Keys returns the keys of the map.
+func (m *Map) Keys() []StackItem {
+	keys := make([]StackItem, len(m.value))
+	for i := range m.value {
+		keys[i] = m.value[i].Key
+	}
+	return keys
+}
+
+// Values returns the values of the map.
+func (m *Map) Values() []StackItem {
+	values := make([]StackItem, len(m.value))
+	for i := range m.value {
+		values[i] = m.value[i].Value
+	}
+	return values
+}
+
+func (m *Map) index(key StackItem) int {
+	switch key.(type) {
+	case *Array, *Struct, *Map:
+		panic(fmt.Sprintf("%s can not be used as a map key", key))
+	}
+	for i := range m.value {
+		if m.value[i].Key.Equals(key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// InteropInterface holds a Go value that is passed between syscalls, like a
+// storage context or an iterator. Contracts can only pass it around.
+type InteropInterface struct {
+	value interface{}
+}
+
+// NewInteropInterface returns a new InteropInterface holding v.
+func NewInteropInterface(v interface{}) *InteropInterface {
+	return &InteropInterface{value: v}
+}
+
+// Value implements the StackItem interface.
+func (i *InteropInterface) Value() interface{} { return i.value }
+
+// BigInt implements the StackItem interface.
+func (i *InteropInterface) BigInt() *big.Int {
+	panic("interop interface can not be converted to an integer")
+}
+
+// Bytes implements the StackItem interface.
+func (i *InteropInterface) Bytes() []byte {
+	panic("interop interface can not be converted to a byte array")
+}
+
+// Bool implements the StackItem interface.
+func (i *InteropInterface) Bool() bool { return i.value != nil }
+
+// Equals implements the StackItem interface. Interop interfaces are equal if
+// they hold the same value.
+func (i *InteropInterface) Equals(other StackItem) bool {
+	o, ok := other.(*InteropInterface)
+	if !ok {
+		return false
+	}
+	if i == o {
+		return true
+	}
+	t := reflect.TypeOf(i.value)
+	return t != nil && t == reflect.TypeOf(o.value) && t.Comparable() && i.value == o.value
+}
+
+func (i *InteropInterface) String() string { return fmt.Sprintf("interop(%v)", i.value) }
+
+// NewStackItem converts a Go value to a stack item. It accepts integers,
+// *big.Int, byte slices, strings, booleans, slices of stack items or Go
+// values and stack items themselves, other values become an
+// InteropInterface.
+func NewStackItem(v interface{}) StackItem {
+	switch t := v.(type) {
+	case StackItem:
+		return t
+	case int:
+		return NewIntegerInt64(int64(t))
+	case int64:
+		return NewIntegerInt64(t)
+	case *big.Int:
+		return NewInteger(t)
+	case []byte:
+		return NewByteArray(t)
+	case string:
+		return NewByteArray([]byte(t))
+	case bool:
+		return NewBoolean(t)
+	case []StackItem:
+		return NewArray(t)
+	case []interface{}:
+		items := make([]StackItem, len(t))
+		for i := range t {
+			items[i] = NewStackItem(t[i])
+		}
+		return NewArray(items)
+	default:
+		return NewInteropInterface(t)
+	}
+}
+
+// bytesEqual compares the byte arrays of two items, items that can not be
+// converted to a byte array are never equal.
+func bytesEqual(a, b StackItem) bool {
+	switch b.(type) {
+	case *Array, *Struct, *Map, *InteropInterface:
+		return false
+	}
+	return bytes.Equal(a.Bytes(), b.Bytes())
+}
+
+// bytesToBigInt converts a little endian two's complement byte array to an
+// integer.
+func bytesToBigInt(b []byte) *big.Int {
+	n := new(big.Int)
+	if len(b) == 0 {
+		return n
+	}
+	n.SetBytes(reverse(b))
+	if b[len(b)-1]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+	return n
+}
+
+// bigIntToBytes converts an integer to the shortest little endian two's
+// complement byte array, zero is an empty byte array.
+func bigIntToBytes(n *big.Int) []byte {
+	if n.Sign() == 0 {
+		return []byte{}
+	}
+	if n.Sign() > 0 {
+		b := n.Bytes()
+		if b[0]&0x80 != 0 {
+			b = append([]byte{0}, b...)
+		}
+		return reverse(b)
+	}
+	// Add 2^(8*size) to get the two's complement of the negative number in
+	// size bytes, with room for the sign bit.
+	size := len(n.Bytes()) + 1
+	b := new(big.Int).Add(n, new(big.Int).Lsh(big.NewInt(1), uint(size*8))).Bytes()
+	for len(b) < size {
+		b = append([]byte{0}, b...)
+	}
+	for len(b) > 1 && b[0] == 0xff && b[1]&0x80 != 0 {
+		b = b[1:]
+	}
+	return reverse(b)
+}
+
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
@@ -0,0 +1,139 @@
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+func TestStackItemConversions(t *testing.T) {
+	var cases = []struct {
+		item    StackItem
+		integer int64
+		bytes   []byte
+		boolean bool
+	}{
+		{item: NewIntegerInt64(0), integer: 0, bytes: []byte{}, boolean: false},
+		{item: NewIntegerInt64(128), integer: 128, bytes: []byte{0x80, 0x00}, boolean: true},
+		{item: NewIntegerInt64(-1), integer: -1, bytes: []byte{0xff}, boolean: true},
+		{item: NewIntegerInt64(-129), integer: -129, bytes: []byte{0x7f, 0xff}, boolean: true},
+		{item: NewByteArray([]byte{}), integer: 0, bytes: []byte{}, boolean: false},
+		{item: NewByteArray([]byte{0, 0}), integer: 0, bytes: []byte{0, 0}, boolean: false},
+		{item: NewByteArray([]byte{0xff, 0x00}), integer: 255, bytes: []byte{0xff, 0x00}, boolean: true},
+		{item: NewByteArray([]byte{0x00, 0x80}), integer: -32768, bytes: []byte{0x00, 0x80}, boolean: true},
+		{item: NewBoolean(true), integer: 1, bytes: []byte{1}, boolean: true},
+		{item: NewBoolean(false), integer: 0, bytes: []byte{}, boolean: false},
+	}
+
+	for _, c := range cases {
+		if n := c.item.BigInt(); n.Cmp(big.NewInt(c.integer)) != 0 {
+			t.Errorf("%s: expected integer %d, got %s", c.item, c.integer, n)
+		}
+		if b := c.item.Bytes(); !bytes.Equal(b, c.bytes) {
+			t.Errorf("%s: expected bytes %x, got %x", c.item, c.bytes, b)
+		}
+		if b := c.item.Bool(); b != c.boolean {
+			t.Errorf("%s: expected bool %v, got %v", c.item, c.boolean, b)
+		}
+	}
+
+	// Byte arrays that are too long to be an integer are always true.
+	if !NewByteArray(make([]byte, MaxSizeForBigInteger+1)).Bool() {
+		t.Error("expected a long byte array to be true")
+	}
+}
+
+func TestStackItemEquals(t *testing.T) {
+	array := NewArray([]StackItem{NewIntegerInt64(1)})
+	m := NewMap()
+	var cases = []struct {
+		a, b  StackItem
+		equal bool
+	}{
+		{NewIntegerInt64(1), NewIntegerInt64(1), true},
+		{NewIntegerInt64(1), NewByteArray([]byte{1}), true},
+		{NewIntegerInt64(0), NewByteArray([]byte{}), true},
+		{NewIntegerInt64(0), NewByteArray([]byte{0}), false},
+		{NewBoolean(true), NewIntegerInt64(1), true},
+		{NewBoolean(false), NewByteArray([]byte{}), true},
+		{NewByteArray([]byte("a")), NewByteArray([]byte("a")), true},
+		{NewByteArray([]byte("a")), array, false},
+		{array, array, true},
+		{array, NewArray([]StackItem{NewIntegerInt64(1)}), false},
+		{NewStruct([]StackItem{NewIntegerInt64(1)}), NewStruct([]StackItem{NewByteArray([]byte{1})}), true},
+		{NewStruct([]StackItem{NewIntegerInt64(1)}), NewStruct([]StackItem{NewIntegerInt64(2)}), false},
+		{NewStruct([]StackItem{}), NewArray([]StackItem{}), false},
+		{m, m, true},
+		{m, NewMap(), false},
+		{NewInteropInterface("ctx"), NewInteropInterface("ctx"), true},
+		{NewInteropInterface([]byte("ctx")), NewInteropInterface([]byte("ctx")), false},
+	}
+
+	for _, c := range cases {
+		if eq := c.a.Equals(c.b); eq != c.equal {
+			t.Errorf("%s == %s: expected %v, got %v", c.a, c.b, c.equal, eq)
+		}
+	}
+}
+
+func TestStructClone(t *testing.T) {
+	inner := NewStruct([]StackItem{NewIntegerInt64(1)})
+	s := NewStruct([]StackItem{inner, NewByteArray([]byte("a"))})
+
+	clone := s.Clone()
+	inner.value[0] = NewIntegerInt64(2)
+	if !clone.value[0].(*Struct).value[0].Equals(NewIntegerInt64(1)) {
+		t.Fatal("expected nested structs to be cloned")
+	}
+
+	// Structs are cloned when they are stored in an array.
+	v := New()
+	array := NewArray([]StackItem{NewBoolean(false)})
+	v.Estack().Push(array)
+	v.Estack().Push(NewIntegerInt64(0))
+	v.Estack().Push(s)
+	v.LoadScript([]byte{byte(SETITEM)})
+	if err := v.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if array.value[0] == StackItem(s) || !array.value[0].Equals(s) {
+		t.Fatal("expected SETITEM to store a clone of the struct")
+	}
+}
+
+func TestMap(t *testing.T) {
+	m := NewMap()
+	m.Set(NewByteArray([]byte{1}), NewByteArray([]byte("a")))
+	m.Set(NewIntegerInt64(1), NewByteArray([]byte("b")))
+	m.Set(NewByteArray([]byte("c")), NewIntegerInt64(3))
+
+	if m.Len() != 2 {
+		t.Fatalf("expected equal keys to be the same key, got %d elements", m.Len())
+	}
+	if item, ok := m.Get(NewBoolean(true)); !ok || !item.Equals(NewByteArray([]byte("b"))) {
+		t.Fatalf("expected b, got %v", item)
+	}
+	m.Delete(NewByteArray([]byte{1}))
+	if _, ok := m.Get(NewIntegerInt64(1)); ok || m.Len() != 1 {
+		t.Fatal("expected the key to be deleted")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected an array key to panic")
+		}
+	}()
+	m.Set(NewArray(nil), NewIntegerInt64(1))
+}
+
+func TestNewStackItem(t *testing.T) {
+	item := NewStackItem([]interface{}{1, "a", true, []byte{2}})
+	expect := NewStruct([]StackItem{NewIntegerInt64(1), NewByteArray([]byte("a")), NewBoolean(true), NewByteArray([]byte{2})})
+	items := item.(*Array).value
+	if !NewStruct(items).Equals(expect) {
+		t.Fatalf("expected %s, got %s", expect, item)
+	}
+	if _, ok := NewStackItem(struct{}{}).(*InteropInterface); !ok {
+		t.Fatal("expected other values to become an interop interface")
+	}
+}
@@ -0,0 +1,26 @@
+package vm
+
+// State is the execution state of the VM.
+type State uint8
+
+// Available states.
+const (
+	// NoneState is the state of a VM that is executing a script or has not
+	// been started yet.
+	NoneState State = iota
+	// HaltState is the state of a VM that executed its script successfully.
+	HaltState
+	// FaultState is the state of a VM that stopped on an error.
+	FaultState
+)
+
+func (s State) String() string {
+	switch s {
+	case HaltState:
+		return "HALT"
+	case FaultState:
+		return "FAULT"
+	default:
+		return "NONE"
+	}
+}
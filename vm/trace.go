@@ -0,0 +1,157 @@
+package vm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/CityOfZion/neo-go/pkg/util"
+)
+
+// TraceStep is a step of a traced execution, see VM.SetTrace.
+type TraceStep struct {
+	// Step is the number of the step, starting at 1.
+	Step int `json:"step"`
+
+	// Script is the hash of the script the instruction is part of.
+	Script string `json:"script"`
+
+	Offset int    `json:"offset"`
+	Opcode string `json:"opcode"`
+
+	// Source is the position in the source code of the instruction, if it
+	// is known.
+	Source string `json:"source,omitempty"`
+
+	// Depth is the size of the invocation stack before the instruction.
+	Depth int `json:"depth"`
+
+	// Stack is the evaluation stack after the instruction, top first.
+	Stack []TraceItem `json:"stack"`
+
+	// GasConsumed is the GAS consumed after the instruction.
+	GasConsumed string `json:"gas"`
+
+	State string `json:"state"`
+	Error string `json:"error,omitempty"`
+}
+
+// TraceItem is a stack item in a trace. The value of an integer is in
+// decimal, of a byte array in hex and of a boolean true or false. Arrays
+// and structs have their items, maps their entries. An array, struct or map
+// that holds itself has Ref set instead, the number of levels up to where it
+// holds itself, 1 for the item that holds it directly.
+type TraceItem struct {
+	Type    string       `json:"type"`
+	Value   string       `json:"value,omitempty"`
+	Items   []TraceItem  `json:"items,omitempty"`
+	Entries []TraceEntry `json:"entries,omitempty"`
+	Ref     int          `json:"ref,omitempty"`
+}
+
+// TraceEntry is an entry of a map in a trace.
+type TraceEntry struct {
+	Key   TraceItem `json:"key"`
+	Value TraceItem `json:"value"`
+}
+
+// NewTraceItem returns the trace representation of a stack item.
+func NewTraceItem(item StackItem) TraceItem {
+	return newTraceItem(item, nil)
+}
+
+// newTraceItem returns the trace representation of an item that is held by
+// the given parents, the one holding it directly last.
+func newTraceItem(item StackItem, parents []StackItem) TraceItem {
+	switch t := item.(type) {
+	case *Integer:
+		return TraceItem{Type: "Integer", Value: t.String()}
+	case *ByteArray:
+		return TraceItem{Type: "ByteArray", Value: fmt.Sprintf("%x", t.value)}
+	case *Boolean:
+		return TraceItem{Type: "Boolean", Value: t.String()}
+	case *Array:
+		if ref := traceRef(item, parents); ref > 0 {
+			return TraceItem{Type: "Array", Ref: ref}
+		}
+		return TraceItem{Type: "Array", Items: newTraceItems(t.value, append(parents, item))}
+	case *Struct:
+		if ref := traceRef(item, parents); ref > 0 {
+			return TraceItem{Type: "Struct", Ref: ref}
+		}
+		return TraceItem{Type: "Struct", Items: newTraceItems(t.value, append(parents, item))}
+	case *Map:
+		if ref := traceRef(item, parents); ref > 0 {
+			return TraceItem{Type: "Map", Ref: ref}
+		}
+		parents = append(parents, item)
+		entries := make([]TraceEntry, len(t.value))
+		for i, e := range t.value {
+			entries[i] = TraceEntry{Key: newTraceItem(e.Key, parents), Value: newTraceItem(e.Value, parents)}
+		}
+		return TraceItem{Type: "Map", Entries: entries}
+	default:
+		return TraceItem{Type: "InteropInterface", Value: fmt.Sprint(item.Value())}
+	}
+}
+
+// traceRef returns the number of levels up to where the item holds itself,
+// or 0 if none of its parents is the item itself.
+func traceRef(item StackItem, parents []StackItem) int {
+	for i := len(parents) - 1; i >= 0; i-- {
+		if parents[i] == item {
+			return len(parents) - i
+		}
+	}
+	return 0
+}
+
+func newTraceItems(items []StackItem, parents []StackItem) []TraceItem {
+	trace := make([]TraceItem, len(items))
+	for i, item := range items {
+		trace[i] = newTraceItem(item, parents)
+	}
+	return trace
+}
+
+// tracer writes the steps of an execution as lines of JSON.
+type tracer struct {
+	enc    *json.Encoder
+	source func(script util.Uint160, offset int) string
+	step   int
+}
+
+// SetTrace makes the VM write a trace of the execution to w, a line of JSON
+// with a TraceStep for every instruction it executes. The source function
+// returns the position in the source code of an offset in a script, it may
+// be nil. Errors writing the trace are ignored. A nil writer stops tracing.
+func (v *VM) SetTrace(w io.Writer, source func(script util.Uint160, offset int) string) {
+	if w == nil {
+		v.tracer = nil
+		return
+	}
+	v.tracer = &tracer{enc: json.NewEncoder(w), source: source}
+}
+
+// trace writes the step that executed the instruction at the offset of the
+// script.
+func (t *tracer) trace(v *VM, script util.Uint160, depth, offset int, op Instruction) {
+	t.step++
+	step := TraceStep{
+		Step:        t.step,
+		Script:      script.String(),
+		Offset:      offset,
+		Opcode:      op.String(),
+		Depth:       depth,
+		Stack:       newTraceItems(v.estack.Items(), nil),
+		GasConsumed: v.gasConsumed.String(),
+		State:       v.state.String(),
+	}
+	if t.source != nil {
+		step.Source = t.source(script, offset)
+	}
+	if v.err != nil {
+		step.Error = v.err.Error()
+	}
+	t.enc.Encode(step)
+}
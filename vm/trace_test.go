@@ -0,0 +1,86 @@
+package vm
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/CityOfZion/neo-go/pkg/util"
+)
+
+func TestTrace(t *testing.T) {
+	script, err := Assemble(strings.NewReader(`
+		PUSH 2
+		PUSH 3
+		ADD
+		THROW`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	v := New()
+	v.LoadScript(script)
+	v.SetTrace(buf, func(_ util.Uint160, offset int) string {
+		return "main.go:" + strconv.Itoa(offset)
+	})
+	v.Run()
+
+	var steps []TraceStep
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var step TraceStep
+		if err := dec.Decode(&step); err != nil {
+			t.Fatal(err)
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) != 4 {
+		t.Fatalf("expected 4 steps, got %d", len(steps))
+	}
+	add := steps[2]
+	if add.Opcode != "ADD" || add.Offset != 2 || add.Source != "main.go:2" || add.Depth != 1 {
+		t.Fatalf("unexpected step %+v", add)
+	}
+	if len(add.Stack) != 1 || add.Stack[0].Type != "Integer" || add.Stack[0].Value != "5" {
+		t.Fatalf("expected the stack [5], got %+v", add.Stack)
+	}
+	if last := steps[3]; last.State != "FAULT" || last.Error == "" {
+		t.Fatalf("expected a fault in the last step, got %+v", last)
+	}
+}
+
+// An array that holds itself is traced with a back-reference.
+func TestTraceCycle(t *testing.T) {
+	script, err := Assemble(strings.NewReader(`
+		PUSH0
+		NEWARRAY
+		DUP
+		DUP
+		APPEND
+		RET`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	v := New()
+	v.LoadScript(script)
+	v.SetTrace(buf, nil)
+	if err := v.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	var step TraceStep
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if err := json.Unmarshal(lines[len(lines)-1], &step); err != nil {
+		t.Fatal(err)
+	}
+	if len(step.Stack) != 1 {
+		t.Fatalf("expected 1 item on the stack, got %+v", step.Stack)
+	}
+	arr := step.Stack[0]
+	if arr.Type != "Array" || len(arr.Items) != 1 || arr.Items[0].Type != "Array" || arr.Items[0].Ref != 1 {
+		t.Fatalf("expected an array holding a reference to itself, got %+v", arr)
+	}
+}
@@ -0,0 +1,902 @@
+package vm
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/CityOfZion/neo-go/pkg/util"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// VM is the NEO virtual machine. It executes compiled scripts with the
+// semantics of the NEO 2.x execution engine.
+type VM struct {
+	state State
+	err   error
+
+	istack []*Context // invocation stack.
+	estack *Stack     // evaluation stack of the executing context.
+	astack *Stack     // alt stack.
+	rstack *Stack     // result stack, the evaluation stack when no script is loaded.
+
+	// registered syscall implementations.
+	interop *InteropService
+
+	// getScript returns the script of a contract for APPCALL and TAILCALL.
+	getScript func(util.Uint160) []byte
+
+	// message is the data that CHECKSIG and CHECKMULTISIG verify the
+	// signatures of.
+	message []byte
+
+	limits      Limits
+	gasLimit    util.Fixed8
+	gasConsumed util.Fixed8
+
+	// tracer writes a trace of the execution if it is set.
+	tracer *tracer
+}
+
+// New returns a new VM, load a script with LoadScript to execute it.
+func New() *VM {
+	s := NewStack()
+	return &VM{
+		estack:  s,
+		rstack:  s,
+		astack:  NewStack(),
+		interop: NewInteropService(),
+		limits:  DefaultLimits,
+	}
+}
+
+// LoadScript loads the script on the invocation stack, it is executed
+// before the scripts that were loaded earlier continue.
+func (v *VM) LoadScript(script []byte) {
+	v.loadContext(NewContext(script), v.estack)
+	v.state = NoneState
+}
+
+// loadContext pushes the context on the invocation stack, it executes with
+// the given evaluation stack.
+func (v *VM) loadContext(ctx *Context, estack *Stack) {
+	ctx.estack = estack
+	v.istack = append(v.istack, ctx)
+	v.estack = estack
+}
+
+// unloadContext pops the executing context from the invocation stack. The
+// return values of a context with an isolated evaluation stack are moved
+// to the stack of the context that continues, unless drop is set.
+func (v *VM) unloadContext(drop bool) error {
+	ctx := v.Context()
+	v.istack = v.istack[:len(v.istack)-1]
+	v.estack = v.rstack
+	if c := v.Context(); c != nil {
+		v.estack = c.estack
+	}
+	if drop || ctx.estack == v.estack {
+		return nil
+	}
+	n := ctx.rvcount
+	if n < 0 {
+		n = ctx.estack.Len()
+	}
+	return moveItems(ctx.estack, v.estack, n)
+}
+
+// LoadFile loads the script in the given file.
+func (v *VM) LoadFile(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	v.LoadScript(b)
+	return nil
+}
+
+// RegisterInteropFunc registers the implementation of the given syscall.
+func (v *VM) RegisterInteropFunc(api string, f InteropFunc) {
+	v.interop.Register(api, f)
+}
+
+// SetInteropService replaces the registry of syscall implementations.
+func (v *VM) SetInteropService(s *InteropService) {
+	v.interop = s
+}
+
+// InteropService returns the registry of syscall implementations.
+func (v *VM) InteropService() *InteropService {
+	return v.interop
+}
+
+// SetScriptGetter sets the function that returns the script of the
+// contracts that are called with APPCALL and TAILCALL.
+func (v *VM) SetScriptGetter(f func(util.Uint160) []byte) {
+	v.getScript = f
+}
+
+// SetMessage sets the data that CHECKSIG and CHECKMULTISIG verify the
+// signatures of, on the network this is the transaction without its
+// witnesses.
+func (v *VM) SetMessage(msg []byte) {
+	v.message = msg
+}
+
+// SetGasLimit sets the GAS the VM may consume, the VM faults when it would
+// consume more. A limit of 0 does not limit the GAS.
+func (v *VM) SetGasLimit(gas util.Fixed8) {
+	v.gasLimit = gas
+}
+
+// GasConsumed returns the GAS the executed instructions cost.
+func (v *VM) GasConsumed() util.Fixed8 {
+	return v.gasConsumed
+}
+
+// SetLimits sets the execution limits, the VM uses DefaultLimits unless they
+// are changed.
+func (v *VM) SetLimits(l Limits) {
+	v.limits = l
+}
+
+// Estack returns the evaluation stack of the executing context.
+func (v *VM) Estack() *Stack {
+	return v.estack
+}
+
+// Astack returns the alt stack.
+func (v *VM) Astack() *Stack {
+	return v.astack
+}
+
+// Context returns the context that is executing, or nil if there is none.
+func (v *VM) Context() *Context {
+	if len(v.istack) == 0 {
+		return nil
+	}
+	return v.istack[len(v.istack)-1]
+}
+
+// CallingContext returns the context that called the executing one, or nil
+// if there is none.
+func (v *VM) CallingContext() *Context {
+	if len(v.istack) < 2 {
+		return nil
+	}
+	return v.istack[len(v.istack)-2]
+}
+
+// EntryContext returns the context of the script that was loaded first, or
+// nil if there is none.
+func (v *VM) EntryContext() *Context {
+	if len(v.istack) == 0 {
+		return nil
+	}
+	return v.istack[0]
+}
+
+// Depth returns the number of contexts on the invocation stack.
+func (v *VM) Depth() int {
+	return len(v.istack)
+}
+
+// State returns the execution state of the VM.
+func (v *VM) State() State {
+	return v.state
+}
+
+// Error returns the error that made the VM fault.
+func (v *VM) Error() error {
+	return v.err
+}
+
+// Run executes the loaded scripts until the VM halts or faults, it returns
+// the error that made it fault.
+func (v *VM) Run() error {
+	for v.state == NoneState {
+		v.Step()
+	}
+	return v.err
+}
+
+// Step executes the next instruction, it returns the error that made the VM
+// fault.
+func (v *VM) Step() error {
+	switch v.state {
+	case HaltState:
+		return nil
+	case FaultState:
+		return v.err
+	}
+	if len(v.istack) == 0 {
+		v.state = HaltState
+		return nil
+	}
+
+	ctx := v.Context()
+	ip, depth := ctx.ip, len(v.istack)
+	op, operand, err := ctx.Next()
+	if err == nil {
+		err = v.consumeGas(op, operand)
+	}
+	if err == nil {
+		err = v.execute(ctx, op, operand)
+	}
+	if err == nil {
+		err = v.checkLimits()
+	}
+	if err != nil {
+		v.state = FaultState
+		v.err = fmt.Errorf("%s at offset %d: %v", op, ip, err)
+	} else if len(v.istack) == 0 {
+		v.state = HaltState
+	}
+	if v.tracer != nil {
+		v.tracer.trace(v, ctx.ScriptHash(), depth, ip, op)
+	}
+	return v.err
+}
+
+// execute executes a single instruction. Invalid operations panic while
+// executing, the panic is returned as the error.
+func (v *VM) execute(ctx *Context, op Instruction, operand []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	if op >= PUSHBYTES1 && op <= PUSHDATA4 {
+		v.checkItemSize(len(operand))
+		v.estack.Push(NewByteArray(operand))
+		return nil
+	}
+	if op == PUSHM1 || (op >= PUSH1 && op <= PUSH16) {
+		v.estack.Push(NewIntegerInt64(int64(op) - int64(PUSH1) + 1))
+		return nil
+	}
+
+	switch op {
+	case PUSH0:
+		v.estack.Push(NewByteArray([]byte{}))
+
+	// Flow control
+	case NOP:
+	case JMP, JMPIF, JMPIFNOT:
+		offset := v.jumpTarget(ctx, operand)
+		cond := true
+		if op != JMP {
+			cond = v.estack.Pop().Bool() == (op == JMPIF)
+		}
+		if cond {
+			ctx.ip = offset
+		}
+	case CALL:
+		call := NewContext(ctx.script)
+		call.ip = v.jumpTarget(ctx, operand)
+		v.loadContext(call, v.estack)
+	case RET:
+		return v.unloadContext(false)
+	case APPCALL, TAILCALL:
+		hash := operand
+		if bytes.Equal(hash, make([]byte, 20)) {
+			hash = v.estack.Pop().Bytes()
+		}
+		script, err := v.contractScript(hash)
+		if err != nil {
+			return err
+		}
+		estack := v.estack
+		if op == TAILCALL {
+			v.unloadContext(true)
+		}
+		v.loadContext(NewContext(script), estack)
+	case SYSCALL:
+		f, ok := v.interop.Get(string(operand))
+		if !ok {
+			return fmt.Errorf("syscall %s is not registered", operand)
+		}
+		return f(v)
+
+	// Stack isolation
+	case CALL_I:
+		call := NewContext(ctx.script)
+		call.ip = v.jumpTarget(ctx, operand[2:])
+		call.rvcount = int(operand[0])
+		estack := NewStack()
+		if err := moveItems(v.estack, estack, int(operand[1])); err != nil {
+			return err
+		}
+		v.loadContext(call, estack)
+	case CALL_E, CALL_ED, CALL_ET, CALL_EDT:
+		rvcount, pcount := int(operand[0]), int(operand[1])
+		tail := op == CALL_ET || op == CALL_EDT
+		if tail && ctx.rvcount != rvcount {
+			return fmt.Errorf("tail call returns %d items, the context returns %d", rvcount, ctx.rvcount)
+		}
+		hash := operand[2:]
+		if op == CALL_ED || op == CALL_EDT {
+			hash = v.estack.Pop().Bytes()
+		}
+		script, err := v.contractScript(hash)
+		if err != nil {
+			return err
+		}
+		estack := NewStack()
+		if err := moveItems(v.estack, estack, pcount); err != nil {
+			return err
+		}
+		if tail {
+			v.unloadContext(true)
+		}
+		call := NewContext(script)
+		call.rvcount = rvcount
+		v.loadContext(call, estack)
+
+	// Stack
+	case DUPFROMALTSTACK:
+		v.estack.Push(v.astack.Peek(0))
+	case TOALTSTACK:
+		v.astack.Push(v.estack.Pop())
+	case FROMALTSTACK:
+		v.estack.Push(v.astack.Pop())
+	case XDROP:
+		n := v.popIndex()
+		v.estack.Remove(n)
+	case XSWAP:
+		n := v.popIndex()
+		v.estack.Swap(0, n)
+	case XTUCK:
+		n := v.popIndex()
+		if n == 0 {
+			return errors.New("XTUCK needs a positive index")
+		}
+		v.estack.Insert(n, v.estack.Peek(0))
+	case DEPTH:
+		v.estack.Push(NewIntegerInt64(int64(v.estack.Len())))
+	case DROP:
+		v.estack.Pop()
+	case DUP:
+		v.estack.Push(v.estack.Peek(0))
+	case NIP:
+		v.estack.Remove(1)
+	case OVER:
+		v.estack.Push(v.estack.Peek(1))
+	case PICK:
+		n := v.popIndex()
+		v.estack.Push(v.estack.Peek(n))
+	case ROLL:
+		n := v.popIndex()
+		v.estack.Push(v.estack.Remove(n))
+	case ROT:
+		v.estack.Push(v.estack.Remove(2))
+	case SWAP:
+		v.estack.Swap(0, 1)
+	case TUCK:
+		v.estack.Insert(2, v.estack.Peek(0))
+
+	// Splice
+	case CAT:
+		b := v.estack.Pop().Bytes()
+		a := v.estack.Pop().Bytes()
+		v.checkItemSize(len(a) + len(b))
+		v.estack.Push(NewByteArray(append(append([]byte{}, a...), b...)))
+	case SUBSTR:
+		count := v.popIndex()
+		index := v.popIndex()
+		b := v.estack.Pop().Bytes()
+		if index > len(b) {
+			index = len(b)
+		}
+		if index+count > len(b) {
+			count = len(b) - index
+		}
+		v.estack.Push(NewByteArray(append([]byte{}, b[index:index+count]...)))
+	case LEFT:
+		count := v.popIndex()
+		b := v.estack.Pop().Bytes()
+		if count > len(b) {
+			count = len(b)
+		}
+		v.estack.Push(NewByteArray(append([]byte{}, b[:count]...)))
+	case RIGHT:
+		count := v.popIndex()
+		b := v.estack.Pop().Bytes()
+		if count > len(b) {
+			return errors.New("RIGHT count is larger than the byte array")
+		}
+		v.estack.Push(NewByteArray(append([]byte{}, b[len(b)-count:]...)))
+	case SIZE:
+		v.estack.Push(NewIntegerInt64(int64(len(v.estack.Pop().Bytes()))))
+
+	// Bitwise logic
+	case EQUAL:
+		b := v.estack.Pop()
+		a := v.estack.Pop()
+		v.estack.Push(NewBoolean(a.Equals(b)))
+
+	// Arithmetic
+	case INC, DEC, SIGN, NEGATE, ABS, INVERT:
+		x := new(big.Int).Set(v.estack.Pop().BigInt())
+		switch op {
+		case INC:
+			x.Add(x, big.NewInt(1))
+		case DEC:
+			x.Sub(x, big.NewInt(1))
+		case SIGN:
+			x.SetInt64(int64(x.Sign()))
+		case NEGATE:
+			x.Neg(x)
+		case ABS:
+			x.Abs(x)
+		case INVERT:
+			x.Not(x)
+		}
+		checkInteger(x)
+		v.estack.Push(NewInteger(x))
+	case NOT:
+		v.estack.Push(NewBoolean(!v.estack.Pop().Bool()))
+	case NZ:
+		v.estack.Push(NewBoolean(v.estack.Pop().BigInt().Sign() != 0))
+	case BOOLAND, BOOLOR:
+		b := v.estack.Pop().Bool()
+		a := v.estack.Pop().Bool()
+		if op == BOOLAND {
+			v.estack.Push(NewBoolean(a && b))
+		} else {
+			v.estack.Push(NewBoolean(a || b))
+		}
+	case ADD, SUB, MUL, DIV, MOD, SHL, SHR, AND, OR, XOR, MIN, MAX:
+		b := v.estack.Pop().BigInt()
+		a := v.estack.Pop().BigInt()
+		x, err := binaryOp(op, a, b)
+		if err != nil {
+			return err
+		}
+		checkInteger(x)
+		v.estack.Push(NewInteger(x))
+	case NUMEQUAL, NUMNOTEQUAL, LT, GT, LTE, GTE:
+		b := v.estack.Pop().BigInt()
+		a := v.estack.Pop().BigInt()
+		v.estack.Push(NewBoolean(compareOp(op, a.Cmp(b))))
+	case WITHIN:
+		b := v.estack.Pop().BigInt()
+		a := v.estack.Pop().BigInt()
+		x := v.estack.Pop().BigInt()
+		v.estack.Push(NewBoolean(a.Cmp(x) <= 0 && x.Cmp(b) < 0))
+
+	// Crypto
+	case SHA1:
+		h := sha1.Sum(v.estack.Pop().Bytes())
+		v.estack.Push(NewByteArray(h[:]))
+	case SHA256:
+		h := sha256.Sum256(v.estack.Pop().Bytes())
+		v.estack.Push(NewByteArray(h[:]))
+	case HASH160:
+		h := sha256.Sum256(v.estack.Pop().Bytes())
+		r := ripemd160.New()
+		r.Write(h[:])
+		v.estack.Push(NewByteArray(r.Sum(nil)))
+	case HASH256:
+		h := sha256.Sum256(v.estack.Pop().Bytes())
+		h = sha256.Sum256(h[:])
+		v.estack.Push(NewByteArray(h[:]))
+	case CHECKSIG:
+		pubkey := v.estack.Pop().Bytes()
+		sig := v.estack.Pop().Bytes()
+		v.estack.Push(NewBoolean(verifySignature(v.message, sig, pubkey)))
+	case VERIFY:
+		pubkey := v.estack.Pop().Bytes()
+		sig := v.estack.Pop().Bytes()
+		msg := v.estack.Pop().Bytes()
+		v.estack.Push(NewBoolean(verifySignature(msg, sig, pubkey)))
+	case CHECKMULTISIG:
+		pubkeys := v.popByteArrays()
+		sigs := v.popByteArrays()
+		if len(pubkeys) == 0 || len(sigs) == 0 || len(sigs) > len(pubkeys) {
+			return errors.New("invalid number of public keys or signatures")
+		}
+		// Signatures must be in the same order as their public keys.
+		i, j := 0, 0
+		for i < len(sigs) && len(sigs)-i <= len(pubkeys)-j {
+			if verifySignature(v.message, sigs[i], pubkeys[j]) {
+				i++
+			}
+			j++
+		}
+		v.estack.Push(NewBoolean(i == len(sigs)))
+
+	// Array
+	case ARRAYSIZE:
+		switch t := v.estack.Pop().(type) {
+		case *Array:
+			v.estack.Push(NewIntegerInt64(int64(len(t.value))))
+		case *Struct:
+			v.estack.Push(NewIntegerInt64(int64(len(t.value))))
+		case *Map:
+			v.estack.Push(NewIntegerInt64(int64(t.Len())))
+		default:
+			v.estack.Push(NewIntegerInt64(int64(len(t.Bytes()))))
+		}
+	case PACK:
+		n := v.popIndex()
+		v.checkArraySize(n)
+		if n > v.estack.Len() {
+			return errors.New("PACK count is larger than the stack")
+		}
+		items := make([]StackItem, n)
+		for i := range items {
+			items[i] = v.estack.Pop()
+		}
+		v.estack.Push(NewArray(items))
+	case UNPACK:
+		items := v.popArray()
+		for i := len(items) - 1; i >= 0; i-- {
+			v.estack.Push(items[i])
+		}
+		v.estack.Push(NewIntegerInt64(int64(len(items))))
+	case PICKITEM:
+		key := v.estack.Pop()
+		if m, ok := v.estack.Peek(0).(*Map); ok {
+			v.estack.Pop()
+			item, ok := m.Get(key)
+			if !ok {
+				return fmt.Errorf("key %s not found", key)
+			}
+			v.estack.Push(item)
+			break
+		}
+		index := indexOf(key)
+		items := v.popArray()
+		if index >= len(items) {
+			return errors.New("index out of range")
+		}
+		v.estack.Push(items[index])
+	case SETITEM:
+		item := v.estack.Pop()
+		if s, ok := item.(*Struct); ok {
+			item = s.Clone()
+		}
+		key := v.estack.Pop()
+		if m, ok := v.estack.Peek(0).(*Map); ok {
+			v.estack.Pop()
+			m.Set(key, item)
+			v.checkArraySize(m.Len())
+			break
+		}
+		index := indexOf(key)
+		items := v.popArray()
+		if index >= len(items) {
+			return errors.New("index out of range")
+		}
+		items[index] = item
+	case NEWARRAY, NEWSTRUCT:
+		// NEWARRAY leaves an array as it is and NEWSTRUCT a struct, an
+		// array is converted to a new struct with the same items and the
+		// other way around.
+		switch t := v.estack.Peek(0).(type) {
+		case *Array:
+			if op == NEWSTRUCT {
+				v.estack.Pop()
+				v.estack.Push(NewStruct(append([]StackItem{}, t.value...)))
+			}
+		case *Struct:
+			if op == NEWARRAY {
+				v.estack.Pop()
+				v.estack.Push(NewArray(append([]StackItem{}, t.value...)))
+			}
+		default:
+			n := v.popIndex()
+			v.checkArraySize(n)
+			items := make([]StackItem, n)
+			for i := range items {
+				items[i] = NewBoolean(false)
+			}
+			if op == NEWARRAY {
+				v.estack.Push(NewArray(items))
+			} else {
+				v.estack.Push(NewStruct(items))
+			}
+		}
+	case APPEND:
+		item := v.estack.Pop()
+		if s, ok := item.(*Struct); ok {
+			item = s.Clone()
+		}
+		switch t := v.estack.Pop().(type) {
+		case *Array:
+			v.checkArraySize(len(t.value) + 1)
+			t.value = append(t.value, item)
+		case *Struct:
+			v.checkArraySize(len(t.value) + 1)
+			t.value = append(t.value, item)
+		default:
+			return fmt.Errorf("can not append to %s", t)
+		}
+	case REVERSE:
+		items := v.popArray()
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	case REMOVE:
+		key := v.estack.Pop()
+		if m, ok := v.estack.Peek(0).(*Map); ok {
+			v.estack.Pop()
+			m.Delete(key)
+			break
+		}
+		index := indexOf(key)
+		switch t := v.estack.Pop().(type) {
+		case *Array:
+			t.value = removeItem(t.value, index)
+		case *Struct:
+			t.value = removeItem(t.value, index)
+		default:
+			return fmt.Errorf("can not remove an item from %s", t)
+		}
+	case NEWMAP:
+		v.estack.Push(NewMap())
+	case HASKEY:
+		key := v.estack.Pop()
+		item := v.estack.Pop()
+		if m, ok := item.(*Map); ok {
+			_, ok := m.Get(key)
+			v.estack.Push(NewBoolean(ok))
+			break
+		}
+		items, ok := arrayItems(item)
+		if !ok {
+			return fmt.Errorf("can not look up a key in %s", item)
+		}
+		v.estack.Push(NewBoolean(indexOf(key) < len(items)))
+	case KEYS:
+		m, ok := v.estack.Pop().(*Map)
+		if !ok {
+			return errors.New("KEYS needs a map")
+		}
+		v.estack.Push(NewArray(m.Keys()))
+	case VALUES:
+		var values []StackItem
+		switch t := v.estack.Pop().(type) {
+		case *Map:
+			values = t.Values()
+		case *Array:
+			values = t.value
+		case *Struct:
+			values = t.value
+		default:
+			return fmt.Errorf("can not get the values of %s", t)
+		}
+		// Structs are copied like they are when they are put in an array.
+		items := make([]StackItem, len(values))
+		for i, item := range values {
+			if s, ok := item.(*Struct); ok {
+				item = s.Clone()
+			}
+			items[i] = item
+		}
+		v.estack.Push(NewArray(items))
+
+	// Exceptions
+	case THROW:
+		return errors.New("THROW")
+	case THROWIFNOT:
+		if !v.estack.Pop().Bool() {
+			return errors.New("THROWIFNOT")
+		}
+
+	default:
+		return fmt.Errorf("unknown opcode 0x%02x", byte(op))
+	}
+	return nil
+}
+
+// jumpTarget returns the offset a jump or call jumps to, its operand is
+// relative to the start of the instruction. The offset of CALL_I is relative
+// to the byte before it, as if it were the operand of a JMP.
+func (v *VM) jumpTarget(ctx *Context, operand []byte) int {
+	offset := ctx.ip - 3 + int(int16(binary.LittleEndian.Uint16(operand)))
+	if offset < 0 || offset > len(ctx.script) {
+		panic(fmt.Sprintf("jump to offset %d is outside of the script", offset))
+	}
+	return offset
+}
+
+// contractScript returns the script of the contract with the given hash.
+func (v *VM) contractScript(hash []byte) ([]byte, error) {
+	u, err := util.Uint160DecodeBytes(hash)
+	if err != nil {
+		return nil, err
+	}
+	var script []byte
+	if v.getScript != nil {
+		script = v.getScript(u)
+	}
+	if script == nil {
+		return nil, fmt.Errorf("contract %s not found", u)
+	}
+	return script, nil
+}
+
+// moveItems moves the top n items of a stack to another one, keeping their
+// order.
+func moveItems(from, to *Stack, n int) error {
+	if from.Len() < n {
+		return fmt.Errorf("expected %d items on the stack, got %d", n, from.Len())
+	}
+	for i := n - 1; i >= 0; i-- {
+		to.Push(from.Peek(i))
+	}
+	for i := 0; i < n; i++ {
+		from.Pop()
+	}
+	return nil
+}
+
+// checkInteger panics if the integer is larger than MaxSizeForBigInteger.
+func checkInteger(n *big.Int) {
+	if size := len(bigIntToBytes(n)); size > MaxSizeForBigInteger {
+		panic(fmt.Sprintf("integer size of %d bytes exceeds %d bytes", size, MaxSizeForBigInteger))
+	}
+}
+
+// popIndex pops an integer that is used as an index or count.
+func (v *VM) popIndex() int {
+	return indexOf(v.estack.Pop())
+}
+
+// indexOf converts an item to an index or count.
+func indexOf(item StackItem) int {
+	n := item.BigInt()
+	if !n.IsInt64() || n.Int64() < 0 || n.Int64() > int64(^uint32(0)>>1) {
+		panic(fmt.Sprintf("invalid index %s", n))
+	}
+	return int(n.Int64())
+}
+
+// popArray pops an array or struct and returns its items.
+func (v *VM) popArray() []StackItem {
+	item := v.estack.Pop()
+	items, ok := arrayItems(item)
+	if !ok {
+		panic(fmt.Sprintf("%s is not an array", item))
+	}
+	return items
+}
+
+// popByteArrays pops the operands of CHECKMULTISIG, either an array or a
+// count followed by that many items.
+func (v *VM) popByteArrays() [][]byte {
+	var items []StackItem
+	if a, ok := arrayItems(v.estack.Peek(0)); ok {
+		v.estack.Pop()
+		items = a
+	} else {
+		n := v.popIndex()
+		for i := 0; i < n; i++ {
+			items = append(items, v.estack.Pop())
+		}
+	}
+	b := make([][]byte, len(items))
+	for i := range items {
+		b[i] = items[i].Bytes()
+	}
+	return b
+}
+
+func arrayItems(item StackItem) ([]StackItem, bool) {
+	switch t := item.(type) {
+	case *Array:
+		return t.value, true
+	case *Struct:
+		return t.value, true
+	}
+	return nil, false
+}
+
+func removeItem(items []StackItem, index int) []StackItem {
+	if index >= len(items) {
+		panic("index out of range")
+	}
+	return append(items[:index], items[index+1:]...)
+}
+
+func binaryOp(op Instruction, a, b *big.Int) (*big.Int, error) {
+	x := new(big.Int)
+	switch op {
+	case ADD:
+		x.Add(a, b)
+	case SUB:
+		x.Sub(a, b)
+	case MUL:
+		x.Mul(a, b)
+	case DIV, MOD:
+		if b.Sign() == 0 {
+			return nil, errors.New("division by zero")
+		}
+		// Division truncates towards zero like it does in C#.
+		if op == DIV {
+			x.Quo(a, b)
+		} else {
+			x.Rem(a, b)
+		}
+	case SHL, SHR:
+		if !b.IsInt64() || b.Int64() > 256 || b.Int64() < -256 {
+			return nil, fmt.Errorf("invalid shift %s", b)
+		}
+		n := b.Int64()
+		if op == SHR {
+			n = -n
+		}
+		if n >= 0 {
+			x.Lsh(a, uint(n))
+		} else {
+			x.Rsh(a, uint(-n))
+		}
+	case AND:
+		x.And(a, b)
+	case OR:
+		x.Or(a, b)
+	case XOR:
+		x.Xor(a, b)
+	case MIN:
+		if a.Cmp(b) < 0 {
+			x.Set(a)
+		} else {
+			x.Set(b)
+		}
+	case MAX:
+		if a.Cmp(b) > 0 {
+			x.Set(a)
+		} else {
+			x.Set(b)
+		}
+	}
+	return x, nil
+}
+
+func compareOp(op Instruction, cmp int) bool {
+	switch op {
+	case NUMEQUAL:
+		return cmp == 0
+	case NUMNOTEQUAL:
+		return cmp != 0
+	case LT:
+		return cmp < 0
+	case GT:
+		return cmp > 0
+	case LTE:
+		return cmp <= 0
+	default:
+		return cmp >= 0
+	}
+}
+
+// verifySignature verifies a 64 byte secp256r1 signature of the SHA256 hash
+// of msg with a compressed or uncompressed public key.
+func verifySignature(msg, sig, pubkey []byte) bool {
+	if len(sig) != 64 {
+		return false
+	}
+	curve := elliptic.P256()
+	var x, y *big.Int
+	if len(pubkey) == 33 {
+		x, y = elliptic.UnmarshalCompressed(curve, pubkey)
+	} else {
+		x, y = elliptic.Unmarshal(curve, pubkey)
+	}
+	if x == nil {
+		return false
+	}
+	h := sha256.Sum256(msg)
+	key := &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+	return ecdsa.Verify(key, h[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:]))
+}